@@ -1,63 +1,296 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/vintcessun/WE-Assistant/bot"
 	"github.com/vintcessun/WE-Assistant/config"
 	"github.com/vintcessun/WE-Assistant/logic"
 	"github.com/vintcessun/WE-Assistant/utils"
 	"github.com/LagrangeDev/LagrangeGo/client"
-	"github.com/LagrangeDev/LagrangeGo/client/auth"
 )
 
+// DefaultShutdownTimeout 默认的优雅关闭超时时间
+const DefaultShutdownTimeout = 30 * time.Second
+
+// botStopTimeout 停止账号时至少可用的时间，用于保存登录签名并释放客户端
+const botStopTimeout = 10 * time.Second
+
 // Container 依赖注入容器
 type Container struct {
-	config       *config.Config
-	logger       *utils.ProtocolLogger
-	client       *client.QQClient
-	bot          *bot.Bot
-	logicManager *logic.LogicManager
+	config          *config.Config
+	logger          *utils.ProtocolLogger
+	bots            []*bot.Bot
+	logicManager    *logic.LogicManager
+	lifecycle       *Lifecycle
+	shutdownTimeout time.Duration
+	flags           *config.Flags
+	watcher         *config.Watcher
+	authorizer      *logic.Authorizer
+	rateLimiter     *logic.RateLimiter
 }
 
 // NewContainer 创建新的容器实例
 func NewContainer() *Container {
-	return &Container{}
+	return &Container{
+		lifecycle:       NewLifecycle(),
+		shutdownTimeout: DefaultShutdownTimeout,
+	}
+}
+
+// UseFlags 使用命令行参数指定配置文件并覆盖配置，需在 Initialize 之前调用
+func (c *Container) UseFlags(flags *config.Flags) {
+	c.flags = flags
 }
 
 // Initialize 初始化所有依赖
 func (c *Container) Initialize() error {
-	c.config = &config.Config{}
+	// 加载并检查配置，所有问题一次性报告
+	cfg, err := config.LoadWithOverrides(c.flags)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	config.GlobalConfig = cfg
+	c.config = cfg
+
+	// 初始化日志
+	logConfig, err := newLogConfig(c.config.Log)
+	if err != nil {
+		return fmt.Errorf("日志配置错误: %w", err)
+	}
+	utils.InitWithConfig(logConfig)
 	c.logger = utils.GetProtocolLogger()
+	for _, warning := range c.config.Warnings() {
+		utils.Warnf("配置警告: %v", warning)
+	}
 
-	// 初始化配置
-	config.Init()
-	c.config = config.GlobalConfig
+	// 渲染服务器
+	utils.GetFakeQQServer().SetConfig(newFakeQQConfig(c.config.Render))
 
-	// 初始化日志
-	utils.Init()
+	// 为每个账号创建独立的客户端和Bot
+	clients := make([]*client.QQClient, 0)
+	for _, botConfig := range c.config.GetBots() {
+		b, err := c.newBot(botConfig)
+		if err != nil {
+			return err
+		}
+		c.bots = append(c.bots, b)
+		clients = append(clients, b.Client())
+	}
+	if len(c.bots) == 0 {
+		return errors.New("未配置任何账号")
+	}
+
+	// 创建逻辑管理器，所有账号共享同一个路由器
+	c.logicManager = logic.NewLogicManager(clients...)
+	c.logicManager.SetDispatcherConfig(newDispatcherConfig(c.config.Dispatch))
+	c.setupAdmin()
+	pluginStore, err := logic.NewPluginStateStore(c.config.Plugins.StateFile)
+	if err != nil {
+		return err
+	}
+	c.logicManager.GetPluginManager().SetStateStore(pluginStore)
+	c.applyLiveConfig(c.config)
+
+	// 签名刷新时发布事件
+	for _, b := range c.bots {
+		b.GetAuthManager().OnSigRefreshed(logic.PublishSigRefreshed)
+	}
+
+	c.registerLifecycle()
+
+	return nil
+}
+
+// setupAdmin 安装白名单与限流中间件，白名单由 applyLiveConfig 设置，限流参数按消息从 ctx.Config() 获取
+func (c *Container) setupAdmin() {
+	c.authorizer = logic.NewAuthorizer(nil)
+	c.rateLimiter = logic.NewRateLimiter(0, time.Minute)
+	// 限流参数可按群与用户覆盖
+	c.rateLimiter.SetLimitFunc(func(ctx *logic.MessageContext) (int, time.Duration) {
+		scope := ctx.Config()
+		return scope.RateLimit, scope.RateLimitWindow
+	})
+	c.logicManager.UseMiddleware(c.authorizer.Middleware())
+	c.logicManager.UseMiddleware(c.rateLimiter.Middleware())
+}
+
+// applyLiveConfig 应用可在运行时生效的配置：[admin]、[llm]、[chat] 以及按群与用户的覆盖
+func (c *Container) applyLiveConfig(cfg *config.Config) {
+	c.logicManager.SetConfig(cfg)
+
+	admin := cfg.Admin
+	c.logicManager.SetAdmins(admin.Owners, admin.Admins)
+	// 白名单为空时不限制，主人与管理员总是允许使用
+	allowed := append(append(append([]uint32{}, admin.AllowedUsers...), admin.Owners...), admin.Admins...)
+	c.authorizer.SetAllowedUsers(allowed)
+	c.authorizer.SetEnabled(len(admin.AllowedUsers) > 0)
+
+	// 群聊系统提示词随配置一起替换，由 ctx.SystemPrompt() 按群与用户解析
+	utils.SetLLMConfig(newLLMConfig(cfg.LLM))
+}
+
+// registerLifecycle 按依赖顺序注册组件：FakeQQ-UI 服务器 -> 各账号 Bot -> 逻辑管理器 -> 配置热重载
+// 关闭时先停止配置热重载，逻辑管理器随后停止并等待处理中的消息，FakeQQ-UI 服务器最后停止
+func (c *Container) registerLifecycle() {
+	// FakeQQ-UI 服务器在首次生成聊天图片时按需启动，这里只负责停止
+	c.lifecycle.Append(Hook{
+		Name: "FakeQQ-UI服务器",
+		Stop: func(ctx context.Context) error {
+			return utils.GetFakeQQServer().Stop()
+		},
+	})
+
+	for _, b := range c.bots {
+		c.lifecycle.Append(Hook{
+			Name: fmt.Sprintf("账号 %d", b.Account()),
+			Start: func(ctx context.Context) error {
+				if err := b.Login(); err != nil {
+					return err
+				}
+				b.Listen()
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				b.Stop()
+				err := b.GetAuthManager().SaveSig()
+				b.Client().Release()
+				return err
+			},
+			// 逻辑管理器用完关闭时间后仍需保存登录签名，否则下次启动需要重新扫码
+			StopTimeout: botStopTimeout,
+		})
+	}
+
+	c.lifecycle.Append(Hook{
+		Name: "逻辑管理器",
+		Start: func(ctx context.Context) error {
+			logic.Manager = c.logicManager
+			// 单个插件加载失败不影响其他插件与账号
+			if err := c.logicManager.GetPluginManager().Load(c.config.Plugins.Disabled); err != nil {
+				utils.Errorf("部分插件加载失败: %v", err)
+			}
+			c.logicManager.RegisterPluginCommands()
+			c.logicManager.RegisterHelpCommand()
+			c.logicManager.SetupEventListeners()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			return c.logicManager.Shutdown(ctx)
+		},
+	})
+
+	c.watcher = config.NewWatcher(c.flags, c.config)
+	// 先在监视器中按顺序应用新配置，再通知其他订阅者
+	c.watcher.OnReload(func(oldConfig, newConfig *config.Config, changes []config.Change) {
+		c.applyLiveConfig(newConfig)
+	})
+	c.watcher.OnReload(logic.PublishConfigReloaded)
+	c.lifecycle.Append(Hook{
+		Name: "配置热重载",
+		Start: func(ctx context.Context) error {
+			c.watcher.Start()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			c.watcher.Stop()
+			return nil
+		},
+	})
+}
+
+// Start 按依赖顺序启动所有组件
+func (c *Container) Start(ctx context.Context) error {
+	return c.lifecycle.Start(ctx)
+}
+
+// Shutdown 按相反顺序停止所有组件，等待处理中的消息结束，超时后返回错误
+func (c *Container) Shutdown(ctx context.Context) error {
+	return c.lifecycle.Stop(ctx)
+}
+
+// GetLifecycle 获取生命周期管理器，可用于注册额外的组件
+func (c *Container) GetLifecycle() *Lifecycle {
+	return c.lifecycle
+}
+
+// SetShutdownTimeout 设置优雅关闭超时时间
+func (c *Container) SetShutdownTimeout(timeout time.Duration) {
+	c.shutdownTimeout = timeout
+}
+
+// GetShutdownTimeout 获取优雅关闭超时时间
+func (c *Container) GetShutdownTimeout() time.Duration {
+	return c.shutdownTimeout
+}
+
+// newBot 根据账号配置创建Bot
+func (c *Container) newBot(botConfig config.BotConfig) (*bot.Bot, error) {
+	appInfo, err := bot.ResolveAppInfo(botConfig.Platform, botConfig.AppVersion)
+	if err != nil {
+		return nil, fmt.Errorf("账号 %d 协议版本配置错误: %w", botConfig.Account, err)
+	}
 
 	// 创建客户端
-	appInfo := auth.AppList["linux"]["3.2.15-30366"]
-	c.client = client.NewClient(c.config.Bot.Account, c.config.Bot.Password)
-	c.client.SetLogger(c.logger)
-	c.client.UseVersion(appInfo)
-	c.client.AddSignServer(c.config.Bot.SignServer)
-	c.client.UseDevice(auth.NewDeviceInfo(114514))
+	qqClient := client.NewClient(botConfig.Account, botConfig.Password)
+	qqClient.SetLogger(c.logger)
 
 	// 创建Bot
-	c.bot = bot.NewBot(c.client)
-	
-	// 加载签名文件
-	c.bot.GetAuthManager().LoadSig()
+	b := bot.NewBot(qqClient, botConfig)
+	b.GetConnectionManager().SetConfig(newConnectionConfig(c.config.Connection))
+	b.GetLoginManager().SetLoginContext(newLoginContext(c.config.Login, botConfig))
+	qrConfig, err := newQRCodeConfig(c.config.Login)
+	if err != nil {
+		return nil, fmt.Errorf("二维码配置错误: %w", err)
+	}
+	b.GetLoginManager().SetQRCodeProcessor(bot.NewQRCodeProcessorWithConfig(qrConfig))
 
-	// 创建逻辑管理器
-	c.logicManager = logic.NewLogicManager(c.client)
+	// 签名服务器需在设置协议版本前注册，以便接收协议信息
+	b.UseSignServers(botConfig.GetSignServers(), nil)
+	qqClient.UseVersion(appInfo)
 
-	return nil
+	// 加载设备信息
+	if err := b.SetupDevice(botConfig); err != nil {
+		return nil, fmt.Errorf("账号 %d 设备信息初始化失败: %w", botConfig.Account, err)
+	}
+
+	// 加载签名文件
+	sigKey, err := bot.LoadSigKey(botConfig.SigKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("账号 %d 签名密钥加载失败: %w", botConfig.Account, err)
+	}
+	b.GetAuthManager().SetEncryptionKey(sigKey)
+	if err := b.GetAuthManager().LoadSig(); err != nil {
+		utils.Warnf("账号 %d 签名文件不可用，将重新登录: %v", botConfig.Account, err)
+	}
+
+	return b, nil
 }
 
-// GetBot 获取Bot实例
+// GetBot 获取第一个账号的Bot实例
 func (c *Container) GetBot() *bot.Bot {
-	return c.bot
+	return c.bots[0]
+}
+
+// GetBots 获取所有账号的Bot实例
+func (c *Container) GetBots() []*bot.Bot {
+	return c.bots
+}
+
+// GetBotByAccount 根据账号获取Bot实例
+func (c *Container) GetBotByAccount(account uint32) (*bot.Bot, bool) {
+	for _, b := range c.bots {
+		if b.Account() == account {
+			return b, true
+		}
+	}
+	return nil, false
 }
 
 // GetLogicManager 获取逻辑管理器实例
@@ -65,12 +298,20 @@ func (c *Container) GetLogicManager() *logic.LogicManager {
 	return c.logicManager
 }
 
-// GetClient 获取客户端实例
+// GetClient 获取第一个账号的客户端实例
 func (c *Container) GetClient() *client.QQClient {
-	return c.client
+	return c.bots[0].Client()
+}
+
+// GetConfigWatcher 获取配置监视器，可用于注册重新加载回调或手动重新加载
+func (c *Container) GetConfigWatcher() *config.Watcher {
+	return c.watcher
 }
 
-// GetConfig 获取配置实例
+// GetConfig 获取当前生效的配置实例
 func (c *Container) GetConfig() *config.Config {
+	if c.watcher != nil {
+		return c.watcher.Current()
+	}
 	return c.config
 }
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vintcessun/WE-Assistant/utils"
+)
+
+// Hook 生命周期钩子，Start 与 Stop 均可为空
+type Hook struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+	// StopTimeout 停止时至少可用的时间，关闭的截止时间剩余不足时单独计时，为 0 时只使用关闭的截止时间
+	StopTimeout time.Duration
+}
+
+// Lifecycle 生命周期管理器
+// 组件按添加顺序启动，按相反顺序停止，被依赖的组件应当先添加
+type Lifecycle struct {
+	hooks   []Hook
+	started int
+	mu      sync.Mutex
+	logger  utils.Logger
+}
+
+// NewLifecycle 创建新的生命周期管理器
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{
+		logger: utils.GetLogger().WithField("module", "lifecycle"),
+	}
+}
+
+// Append 添加组件
+func (l *Lifecycle) Append(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook)
+}
+
+// Start 依次启动所有组件，某个组件启动失败时停止已启动的组件并返回错误
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for l.started < len(l.hooks) {
+		hook := l.hooks[l.started]
+		if hook.Start != nil {
+			l.logger.Infof("正在启动 %s", hook.Name)
+			if err := hook.Start(ctx); err != nil {
+				startErr := fmt.Errorf("启动 %s 失败: %w", hook.Name, err)
+				if stopErr := l.stop(ctx); stopErr != nil {
+					return errors.Join(startErr, stopErr)
+				}
+				return startErr
+			}
+		}
+		l.started++
+	}
+	return nil
+}
+
+// Stop 按启动的相反顺序停止所有已启动的组件
+// 单个组件停止失败或超时不会影响其他组件，所有失败合并后返回
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stop(ctx)
+}
+
+// stop 停止已启动的组件，调用方需持有锁
+func (l *Lifecycle) stop(ctx context.Context) error {
+	var errs []error
+	for ; l.started > 0; l.started-- {
+		hook := l.hooks[l.started-1]
+		if hook.Stop == nil {
+			continue
+		}
+
+		l.logger.Infof("正在停止 %s", hook.Name)
+		if err := l.stopHook(ctx, hook); err != nil {
+			l.logger.Errorf("停止 %s 失败: %v", hook.Name, err)
+			errs = append(errs, fmt.Errorf("停止 %s 失败: %w", hook.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stopHook 停止单个组件，超过截止时间后不再等待
+// 之前的组件用去了关闭的时间时，设置了 StopTimeout 的组件仍有 StopTimeout 可用
+func (l *Lifecycle) stopHook(ctx context.Context, hook Hook) error {
+	if hook.StopTimeout > 0 {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < hook.StopTimeout {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), hook.StopTimeout)
+			defer cancel()
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- hook.Stop(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"github.com/vintcessun/WE-Assistant/bot"
+	"github.com/vintcessun/WE-Assistant/config"
+	"github.com/vintcessun/WE-Assistant/logic"
+	"github.com/vintcessun/WE-Assistant/utils"
+)
+
+// 将配置文件中的各部分转换为对应组件的配置
+
+// newLogConfig 根据 [log] 创建日志配置
+func newLogConfig(cfg config.LogConfig) (*utils.LogConfig, error) {
+	level, err := utils.ParseLogLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+
+	return &utils.LogConfig{
+		Level:       level,
+		EnableFile:  cfg.EnableFile,
+		EnableColor: cfg.EnableColor,
+		LogDir:      cfg.Dir,
+		LogFile:     cfg.File,
+		MaxSize:     cfg.MaxSize,
+		MaxBackups:  cfg.MaxBackups,
+		MaxAge:      cfg.MaxAge,
+		Format:      cfg.Format,
+	}, nil
+}
+
+// newConnectionConfig 根据 [connection] 创建连接配置
+func newConnectionConfig(cfg config.ConnectionConfig) *bot.ConnectionConfig {
+	return &bot.ConnectionConfig{
+		AutoReconnect:        cfg.AutoReconnect,
+		ReconnectInterval:    cfg.ReconnectInterval,
+		MaxReconnectInterval: cfg.MaxReconnectInterval,
+		ReconnectJitter:      cfg.ReconnectJitter,
+		MaxReconnectTries:    cfg.MaxReconnectTries,
+		HeartbeatInterval:    cfg.HeartbeatInterval,
+		HeartbeatTimeout:     cfg.HeartbeatTimeout,
+		MaxHeartbeatFailures: cfg.MaxHeartbeatFailures,
+	}
+}
+
+// newLoginContext 根据 [login] 创建登录上下文，账号单独配置的二维码刷新次数优先
+func newLoginContext(cfg config.LoginConfig, botConfig config.BotConfig) *bot.LoginContext {
+	loginContext := &bot.LoginContext{
+		MaxRetries:       cfg.MaxRetries,
+		RetryDelay:       cfg.RetryDelay,
+		Timeout:          cfg.Timeout,
+		MaxQRCodeRefresh: cfg.QRCodeMaxRefresh,
+	}
+	if botConfig.QRCodeMaxRefresh != 0 {
+		loginContext.MaxQRCodeRefresh = botConfig.QRCodeMaxRefresh
+	}
+	return loginContext
+}
+
+// newQRCodeConfig 根据 [login] 创建终端二维码配置
+func newQRCodeConfig(cfg config.LoginConfig) (*bot.QRCodeConfig, error) {
+	level, err := bot.ParseQRCodeLevel(cfg.QRCodeLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	qrConfig := bot.DefaultQRCodeConfig()
+	qrConfig.Level = level
+	qrConfig.QuietZone = cfg.QRCodeQuietZone
+	return qrConfig, nil
+}
+
+// newFakeQQConfig 根据 [render] 创建 FakeQQ-UI 服务器配置
+func newFakeQQConfig(cfg config.RenderConfig) *utils.FakeQQConfig {
+	return &utils.FakeQQConfig{
+		Dir:          cfg.Dir,
+		Port:         cfg.Port,
+		MaxPort:      cfg.MaxPort,
+		StartTimeout: cfg.StartTimeout,
+		LogFile:      cfg.LogFile,
+	}
+}
+
+// newLLMConfig 根据 [llm] 创建大模型接口配置，未配置 apiKey 时大模型功能不可用
+func newLLMConfig(cfg config.LLMConfig) *utils.LLMConfig {
+	return &utils.LLMConfig{
+		BaseURL: cfg.BaseURL,
+		APIKey:  cfg.APIKey,
+		Model:   cfg.Model,
+		Timeout: cfg.Timeout,
+	}
+}
+
+// newDispatcherConfig 根据 [dispatch] 创建消息分发配置
+func newDispatcherConfig(cfg config.DispatchConfig) *logic.DispatcherConfig {
+	return &logic.DispatcherConfig{
+		Workers:   cfg.Workers,
+		QueueSize: cfg.QueueSize,
+		Policy:    logic.QueuePolicy(cfg.Policy),
+	}
+}
@@ -1,72 +1,307 @@
 package bot
 
 import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/vintcessun/WE-Assistant/utils"
 	"github.com/LagrangeDev/LagrangeGo/client"
 	"github.com/LagrangeDev/LagrangeGo/client/auth"
 )
 
+// SigPersistConfig 签名持久化配置
+type SigPersistConfig struct {
+	SaveInterval  time.Duration // 定期保存间隔
+	CheckInterval time.Duration // 检查令牌是否刷新的间隔
+	MaxBackups    int           // 保留的签名备份数量
+}
+
+// DefaultSigPersistConfig 默认签名持久化配置
+func DefaultSigPersistConfig() *SigPersistConfig {
+	return &SigPersistConfig{
+		SaveInterval:  10 * time.Minute,
+		CheckInterval: 30 * time.Second,
+		MaxBackups:    3,
+	}
+}
+
+// SigRefreshedHandler 签名刷新回调
+type SigRefreshedHandler func(account uint32)
+
 // AuthManager 处理认证相关逻辑
 type AuthManager struct {
-	client  *client.QQClient
-	sigFile string
-	logger  utils.Logger
+	client          *client.QQClient
+	sigFile         string
+	sigKey          []byte
+	persistConfig   *SigPersistConfig
+	refreshHandlers []SigRefreshedHandler
+	fingerprint     [sha256.Size]byte
+	savedSig        [sha256.Size]byte // 签名文件中令牌的指纹
+	saveMutex       sync.Mutex
+	stopChan        chan struct{}
+	wg              sync.WaitGroup
+	logger          utils.Logger
 }
 
 // NewAuthManager 创建新的认证管理器
 func NewAuthManager(client *client.QQClient, sigFile string) *AuthManager {
 	return &AuthManager{
-		client:  client,
-		sigFile: sigFile,
-		logger:  utils.GetLogger().WithField("module", "auth"),
+		client:        client,
+		sigFile:       sigFile,
+		persistConfig: DefaultSigPersistConfig(),
+		logger:        utils.GetLogger().WithField("module", "auth"),
 	}
 }
 
-// LoadSig 加载签名文件
-func (am *AuthManager) LoadSig() {
-	data, err := os.ReadFile(am.sigFile)
+// SetEncryptionKey 设置签名文件加密密钥，nil 表示不加密
+func (am *AuthManager) SetEncryptionKey(key []byte) {
+	am.sigKey = key
+}
+
+// SetPersistConfig 设置签名持久化配置
+func (am *AuthManager) SetPersistConfig(config *SigPersistConfig) {
+	am.persistConfig = config
+}
+
+// OnSigRefreshed 注册签名刷新回调
+func (am *AuthManager) OnSigRefreshed(handler SigRefreshedHandler) {
+	am.refreshHandlers = append(am.refreshHandlers, handler)
+}
+
+// LoadSig 加载签名文件，主文件不可用时依次尝试备份
+// 文件不存在时返回 nil；全部损坏或无法解密时清空签名并返回错误，之后将使用其他方式登录
+func (am *AuthManager) LoadSig() error {
+	err := am.loadSigFile(am.sigFile)
+	if err == nil {
+		return nil
+	}
+
+	for i := 1; i <= am.persistConfig.MaxBackups; i++ {
+		backup := am.backupFile(i)
+		if backupErr := am.loadSigFile(backup); backupErr == nil {
+			am.logger.Warnf("签名文件不可用 (%v)，已从备份 %s 恢复", err, backup)
+			return nil
+		}
+	}
+
+	if errors.Is(err, os.ErrNotExist) {
+		am.logger.Info("签名文件不存在，将使用其他方式登录")
+		return nil
+	}
+
+	am.client.UseSig(auth.SigInfo{})
+	return err
+}
+
+// loadSigFile 从指定文件加载签名
+func (am *AuthManager) loadSigFile(filename string) error {
+	data, err := os.ReadFile(filename)
 	if err != nil {
-		am.logger.Warnf("读取签名文件失败: %v", err)
-		return
+		if errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return fmt.Errorf("读取签名文件失败: %w", err)
 	}
-	
-	sig, err := auth.UnmarshalSigInfo(data, true)
+
+	raw, err := decodeSigFile(data, am.sigKey)
 	if err != nil {
-		am.logger.Warnf("解析签名文件失败: %v", err)
-		return
+		return err
+	}
+
+	sig, err := auth.UnmarshalSigInfo(raw, true)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSigCorrupted, err)
 	}
-	
+
 	am.client.UseSig(sig)
-	am.logger.Info("签名文件加载成功")
+	am.fingerprint = sigFingerprint(&sig)
+	am.saveMutex.Lock()
+	am.savedSig = am.fingerprint
+	am.saveMutex.Unlock()
+	am.logger.Infof("签名文件 %s 加载成功", filename)
+	return nil
 }
 
 // Dumpsig 保存签名
 func (am *AuthManager) Dumpsig() {
-	if am.client.Sig() == nil {
-		am.logger.Warn("没有可用的签名信息")
+	if err := am.SaveSig(); err != nil {
+		am.logger.Errorf("保存签名失败: %v", err)
 		return
 	}
-	
-	data, err := am.client.Sig().Marshal()
+
+	am.logger.Info("签名文件保存成功")
+}
+
+// SaveSig 序列化签名并原子写入签名文件
+// 令牌与签名文件中的不同时，新文件写入成功后再轮换备份；未变化时只覆盖主文件
+func (am *AuthManager) SaveSig() error {
+	if !am.HasValidSig() {
+		return errors.New("没有可用的签名信息")
+	}
+
+	am.saveMutex.Lock()
+	defer am.saveMutex.Unlock()
+
+	fingerprint := sigFingerprint(am.client.Sig())
+	sig, err := am.client.Sig().Marshal()
 	if err != nil {
-		am.logger.Errorf("序列化签名失败: %v", err)
-		return
+		return fmt.Errorf("序列化签名失败: %w", err)
+	}
+
+	data, err := encodeSigFile(sig, am.sigKey)
+	if err != nil {
+		return fmt.Errorf("加密签名失败: %w", err)
 	}
-	
-	err = os.WriteFile(am.sigFile, data, 0644)
+
+	tmpName, err := writeTempFile(am.sigFile, data, 0600)
 	if err != nil {
-		am.logger.Errorf("写入签名文件失败: %v", err)
+		return fmt.Errorf("写入签名文件失败: %w", err)
+	}
+	previous := ""
+	if fingerprint != am.savedSig {
+		previous = am.keepPrevious()
+	}
+	if err := os.Rename(tmpName, am.sigFile); err != nil {
+		os.Remove(tmpName)
+		if previous != "" {
+			os.Remove(previous)
+		}
+		return fmt.Errorf("写入签名文件失败: %w", err)
+	}
+	if previous != "" {
+		am.rotateBackups(previous)
+	}
+	am.savedSig = fingerprint
+	return nil
+}
+
+// keepPrevious 在替换签名文件前为旧文件创建硬链接或副本，返回其路径
+// 不需要备份或旧文件不存在时返回空字符串
+func (am *AuthManager) keepPrevious() string {
+	if am.persistConfig.MaxBackups <= 0 {
+		return ""
+	}
+	if _, err := os.Stat(am.sigFile); err != nil {
+		return ""
+	}
+
+	previous := am.sigFile + ".prev"
+	os.Remove(previous)
+	if err := linkOrCopy(am.sigFile, previous); err != nil {
+		am.logger.Warnf("备份签名文件失败: %v", err)
+		return ""
+	}
+	return previous
+}
+
+// rotateBackups 轮换签名备份: previous -> sig.bin.1 -> sig.bin.2 ...
+func (am *AuthManager) rotateBackups(previous string) {
+	os.Remove(am.backupFile(am.persistConfig.MaxBackups))
+	for i := am.persistConfig.MaxBackups - 1; i >= 1; i-- {
+		os.Rename(am.backupFile(i), am.backupFile(i+1))
+	}
+	if err := os.Rename(previous, am.backupFile(1)); err != nil {
+		am.logger.Warnf("备份签名文件失败: %v", err)
+	}
+}
+
+// backupFile 获取第 n 个备份文件路径
+func (am *AuthManager) backupFile(n int) string {
+	return fmt.Sprintf("%s.%d", am.sigFile, n)
+}
+
+// StartAutoSave 开始定期保存签名并检测令牌刷新
+// 登录得到的新令牌与签名文件中的不同，会在第一次检查时保存并通知
+func (am *AuthManager) StartAutoSave() {
+	if am.stopChan != nil {
 		return
 	}
-	
-	am.logger.Info("签名文件保存成功")
+	am.stopChan = make(chan struct{})
+
+	am.wg.Add(1)
+	go am.autoSaveLoop(am.stopChan)
+}
+
+// StopAutoSave 停止定期保存
+func (am *AuthManager) StopAutoSave() {
+	if am.stopChan == nil {
+		return
+	}
+	close(am.stopChan)
+	am.wg.Wait()
+	am.stopChan = nil
+}
+
+// autoSaveLoop 定期保存签名，令牌刷新时立即保存并通知
+func (am *AuthManager) autoSaveLoop(stopChan chan struct{}) {
+	defer am.wg.Done()
+
+	saveTicker := time.NewTicker(am.persistConfig.SaveInterval)
+	defer saveTicker.Stop()
+	checkTicker := time.NewTicker(am.persistConfig.CheckInterval)
+	defer checkTicker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-saveTicker.C:
+			if err := am.SaveSig(); err != nil {
+				am.logger.Warnf("定期保存签名失败: %v", err)
+			}
+		case <-checkTicker.C:
+			am.checkRefresh()
+		}
+	}
+}
+
+// checkRefresh 检查令牌是否已刷新
+func (am *AuthManager) checkRefresh() {
+	sig := am.client.Sig()
+	if !hasLoginCache(sig) {
+		return
+	}
+
+	fingerprint := sigFingerprint(sig)
+	if fingerprint == am.fingerprint {
+		return
+	}
+	am.fingerprint = fingerprint
+
+	am.logger.Info("检测到签名已刷新")
+	if err := am.SaveSig(); err != nil {
+		am.logger.Errorf("保存刷新后的签名失败: %v", err)
+	}
+	for _, handler := range am.refreshHandlers {
+		handler(sig.Uin)
+	}
+}
+
+// sigFingerprint 计算签名中会话令牌的指纹，不包含每个数据包都会变化的序列号
+func sigFingerprint(sig *auth.SigInfo) [sha256.Size]byte {
+	h := sha256.New()
+	for _, part := range [][]byte{sig.Tgt, sig.D2, sig.D2Key, sig.TempPwd} {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+
+	var fingerprint [sha256.Size]byte
+	copy(fingerprint[:], h.Sum(nil))
+	return fingerprint
 }
 
 // HasValidSig 检查是否有有效的签名
 func (am *AuthManager) HasValidSig() bool {
-	return am.client.Sig() != nil
+	return hasLoginCache(am.client.Sig())
+}
+
+// hasLoginCache 检查签名中是否包含可用于快速登录的会话信息
+func hasLoginCache(sig *auth.SigInfo) bool {
+	return sig != nil && sig.Uin != 0 && len(sig.D2) > 0
 }
 
 // GetSigFile 获取签名文件路径
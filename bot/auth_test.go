@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LagrangeDev/LagrangeGo/client"
+	"github.com/LagrangeDev/LagrangeGo/client/auth"
+)
+
+// newTestAuthManager 创建使用临时目录签名文件的认证管理器
+func newTestAuthManager(t *testing.T) *AuthManager {
+	t.Helper()
+	am := NewAuthManager(client.NewClientEmpty(), filepath.Join(t.TempDir(), "sig.bin"))
+	am.SetEncryptionKey(deriveSigKey([]byte("secret")))
+	return am
+}
+
+// useTestSig 设置带有指定令牌的签名
+func useTestSig(am *AuthManager, d2 string) {
+	am.client.UseSig(auth.SigInfo{Uin: 10001, D2: []byte(d2), D2Key: []byte("key")})
+}
+
+// loadedD2 加载指定签名文件并返回其中的令牌
+func loadedD2(t *testing.T, am *AuthManager, filename string) string {
+	t.Helper()
+	loader := NewAuthManager(client.NewClientEmpty(), filename)
+	loader.SetEncryptionKey(am.sigKey)
+	if err := loader.loadSigFile(filename); err != nil {
+		t.Fatalf("load %s: %v", filepath.Base(filename), err)
+	}
+	return string(loader.client.Sig().D2)
+}
+
+func TestSaveSigRotatesBackups(t *testing.T) {
+	am := newTestAuthManager(t)
+	for _, d2 := range []string{"a", "b", "c", "d", "e"} {
+		useTestSig(am, d2)
+		if err := am.SaveSig(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := loadedD2(t, am, am.sigFile); got != "e" {
+		t.Errorf("primary = %q, want e", got)
+	}
+	for i, want := range []string{"d", "c", "b"} {
+		if got := loadedD2(t, am, am.backupFile(i+1)); got != want {
+			t.Errorf("backup %d = %q, want %q", i+1, got, want)
+		}
+	}
+	if _, err := os.Stat(am.backupFile(4)); !os.IsNotExist(err) {
+		t.Errorf("only %d backups should be kept", am.persistConfig.MaxBackups)
+	}
+	if _, err := os.Stat(am.sigFile + ".prev"); !os.IsNotExist(err) {
+		t.Error("temporary backup link should be renamed")
+	}
+}
+
+func TestSaveSigFailureKeepsPrimary(t *testing.T) {
+	am := newTestAuthManager(t)
+	useTestSig(am, "a")
+	if err := am.SaveSig(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 签名文件所在目录不可写时写入失败，原有签名文件与备份保持不变
+	dir := filepath.Dir(am.sigFile)
+	if err := os.Chmod(dir, 0500); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(dir, 0700)
+	if f, err := os.CreateTemp(dir, "probe"); err == nil {
+		f.Close()
+		os.Remove(f.Name())
+		t.Skip("directory permissions are not enforced")
+	}
+
+	useTestSig(am, "b")
+	if err := am.SaveSig(); err == nil {
+		t.Fatal("expected a write error")
+	}
+	if got := loadedD2(t, am, am.sigFile); got != "a" {
+		t.Errorf("primary = %q, want a", got)
+	}
+	if _, err := os.Stat(am.backupFile(1)); !os.IsNotExist(err) {
+		t.Error("backups should not rotate when the write fails")
+	}
+}
+
+func TestLoadSigFallsBackToBackup(t *testing.T) {
+	am := newTestAuthManager(t)
+	for _, d2 := range []string{"a", "b"} {
+		useTestSig(am, d2)
+		if err := am.SaveSig(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(am.sigFile, []byte(sigFileMagic+"broken"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewAuthManager(client.NewClientEmpty(), am.sigFile)
+	loader.SetEncryptionKey(am.sigKey)
+	if err := loader.LoadSig(); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(loader.client.Sig().D2); got != "a" {
+		t.Errorf("loaded %q from backup, want a", got)
+	}
+}
+
+func TestSaveSigUnchangedKeepsBackups(t *testing.T) {
+	am := newTestAuthManager(t)
+	for _, d2 := range []string{"a", "b", "b", "b", "b"} {
+		useTestSig(am, d2)
+		if err := am.SaveSig(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := loadedD2(t, am, am.sigFile); got != "b" {
+		t.Errorf("primary = %q, want b", got)
+	}
+	if got := loadedD2(t, am, am.backupFile(1)); got != "a" {
+		t.Errorf("backup 1 = %q, want a", got)
+	}
+	if _, err := os.Stat(am.backupFile(2)); !os.IsNotExist(err) {
+		t.Error("saving an unchanged sig should not rotate backups")
+	}
+
+	// 从文件加载的签名再次保存时同样不轮换
+	loader := NewAuthManager(client.NewClientEmpty(), am.sigFile)
+	loader.SetEncryptionKey(am.sigKey)
+	if err := loader.LoadSig(); err != nil {
+		t.Fatal(err)
+	}
+	if err := loader.SaveSig(); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadedD2(t, am, am.backupFile(1)); got != "a" {
+		t.Errorf("backup 1 = %q after saving the loaded sig, want a", got)
+	}
+}
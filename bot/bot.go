@@ -1,10 +1,11 @@
 package bot
 
 import (
+	"fmt"
+
 	"github.com/vintcessun/WE-Assistant/config"
 	"github.com/vintcessun/WE-Assistant/utils"
 	"github.com/LagrangeDev/LagrangeGo/client"
-	"github.com/LagrangeDev/LagrangeGo/client/auth"
 )
 
 // Bot 使用组合模式而不是继承
@@ -12,27 +13,57 @@ type Bot struct {
 	client        *client.QQClient
 	loginMgr      *LoginManager
 	authMgr       *AuthManager
+	deviceMgr     *DeviceManager
 	connectionMgr *ConnectionManager
+	qrWebServer   *QRCodeWebServer
 }
 
 // Bot 实例
 var QQClient *Bot
 
 // NewBot 创建新的Bot实例
-func NewBot(client *client.QQClient) *Bot {
+func NewBot(client *client.QQClient, cfg config.BotConfig) *Bot {
+	sigFile := cfg.SigFile
+	if sigFile == "" {
+		sigFile = "sig.bin"
+	}
+	deviceFile := cfg.DeviceFile
+	if deviceFile == "" {
+		deviceFile = "device.json"
+	}
+
 	bot := &Bot{
-		client:   client,
-		loginMgr: NewLoginManager(client),
-		authMgr:  NewAuthManager(client, "sig.bin"),
+		client:    client,
+		loginMgr:  NewLoginManager(client, cfg.Password),
+		authMgr:   NewAuthManager(client, sigFile),
+		deviceMgr: NewDeviceManager(client, deviceFile),
 	}
 	
 	// 创建连接管理器并注册默认事件处理器
 	bot.connectionMgr = NewConnectionManager(client)
+	bot.connectionMgr.SetReconnector(bot.loginMgr)
 	bot.connectionMgr.RegisterEventHandler(&DefaultConnectionEventHandler{})
+
+	if cfg.QRCodeMaxRefresh != 0 {
+		loginContext := *bot.loginMgr.GetLoginContext()
+		loginContext.MaxQRCodeRefresh = cfg.QRCodeMaxRefresh
+		bot.loginMgr.SetLoginContext(&loginContext)
+	}
+
+	// 配置了监听地址时通过网页展示登录二维码
+	if cfg.QRCodeWebAddr != "" {
+		bot.qrWebServer = NewQRCodeWebServer(cfg.QRCodeWebAddr)
+		bot.loginMgr.SetQRCodeWebServer(bot.qrWebServer)
+	}
 	
 	return bot
 }
 
+// Account 获取Bot登录的账号
+func (b *Bot) Account() uint32 {
+	return b.client.Uin
+}
+
 // Client 获取内部客户端
 func (b *Bot) Client() *client.QQClient {
 	return b.client
@@ -43,14 +74,19 @@ func (b *Bot) Login() error {
 	return b.loginMgr.Login()
 }
 
-// Listen 开始监听连接状态
+// Listen 开始监听连接状态并定期保存签名
 func (b *Bot) Listen() {
 	b.connectionMgr.StartMonitoring()
+	b.authMgr.StartAutoSave()
 }
 
 // Stop 停止Bot
 func (b *Bot) Stop() {
+	b.authMgr.StopAutoSave()
 	b.connectionMgr.StopMonitoring()
+	if b.qrWebServer != nil {
+		b.qrWebServer.Stop()
+	}
 }
 
 // Dumpsig 保存签名
@@ -73,15 +109,53 @@ func (b *Bot) GetAuthManager() *AuthManager {
 	return b.authMgr
 }
 
-// Init 初始化Bot（向后兼容）
-func Init(logger *utils.ProtocolLogger) {
-	appInfo := auth.AppList["linux"]["3.2.15-30366"]
+// GetDeviceManager 获取设备信息管理器
+func (b *Bot) GetDeviceManager() *DeviceManager {
+	return b.deviceMgr
+}
+
+// SetupDevice 按配置加载、固定或轮换设备信息
+func (b *Bot) SetupDevice(cfg config.BotConfig) error {
+	switch {
+	case cfg.DeviceSeed != 0:
+		return b.deviceMgr.PinDevice(cfg.DeviceSeed)
+	case cfg.RotateDevice:
+		return b.deviceMgr.RotateDevice()
+	default:
+		return b.deviceMgr.LoadDevice()
+	}
+}
+
+// UseSignServers 为客户端启用带故障转移的签名服务器
+func (b *Bot) UseSignServers(servers []config.SignServerConfig, signConfig *SignConfig) *SignServerManager {
+	signMgr := NewSignServerManager(servers, signConfig)
+	b.client.UseSignProvider(signMgr)
+	b.connectionMgr.SetSignServerManager(signMgr)
+	return signMgr
+}
+
+// Init 初始化Bot（向后兼容），设备信息或签名密钥无法加载时返回错误
+func Init(logger *utils.ProtocolLogger) error {
+	appInfo, err := ResolveAppInfo(config.GlobalConfig.Bot.Platform, config.GlobalConfig.Bot.AppVersion)
+	if err != nil {
+		utils.Panicf("协议版本配置错误: %v", err)
+	}
 	qqClientInstance := client.NewClient(config.GlobalConfig.Bot.Account, config.GlobalConfig.Bot.Password)
 	qqClientInstance.SetLogger(logger)
-	qqClientInstance.UseVersion(appInfo)
-	qqClientInstance.AddSignServer(config.GlobalConfig.Bot.SignServer)
-	qqClientInstance.UseDevice(auth.NewDeviceInfo(114514))
 
-	QQClient = NewBot(qqClientInstance)
-	QQClient.authMgr.LoadSig()
+	QQClient = NewBot(qqClientInstance, config.GlobalConfig.Bot)
+	QQClient.UseSignServers(config.GlobalConfig.Bot.GetSignServers(), nil)
+	qqClientInstance.UseVersion(appInfo)
+	if err := QQClient.SetupDevice(config.GlobalConfig.Bot); err != nil {
+		return fmt.Errorf("设备信息初始化失败: %w", err)
+	}
+	sigKey, err := LoadSigKey(config.GlobalConfig.Bot.SigKeyFile)
+	if err != nil {
+		return fmt.Errorf("签名密钥加载失败: %w", err)
+	}
+	QQClient.authMgr.SetEncryptionKey(sigKey)
+	if err := QQClient.authMgr.LoadSig(); err != nil {
+		utils.Warnf("签名文件不可用，将重新登录: %v", err)
+	}
+	return nil
 }
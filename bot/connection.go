@@ -1,11 +1,16 @@
 package bot
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
 	"github.com/vintcessun/WE-Assistant/utils"
 	"github.com/LagrangeDev/LagrangeGo/client"
+	"github.com/LagrangeDev/LagrangeGo/client/packets/wtlogin"
 )
 
 // ConnectionState 连接状态
@@ -16,8 +21,33 @@ const (
 	Connecting
 	Connected
 	Reconnecting
+	Degraded // 心跳失败，连接可能已不可用
 )
 
+// heartbeatCmd 心跳使用的协议命令
+const heartbeatCmd = "trpc.qq_new_tech.status_svc.StatusService.SsoHeartBeat"
+
+// maxHeartbeatHistory 保留的心跳记录数量
+const maxHeartbeatHistory = 60
+
+// HeartbeatRecord 单次心跳记录
+type HeartbeatRecord struct {
+	Time    time.Time
+	Latency time.Duration
+	Error   string
+}
+
+// HeartbeatStats 心跳统计
+type HeartbeatStats struct {
+	LastSuccess time.Time
+	LastLatency time.Duration
+	AvgLatency  time.Duration     // 最近记录中成功心跳的平均延迟
+	Failures    int               // 连续失败次数
+	TotalSent   uint64
+	TotalFailed uint64
+	History     []HeartbeatRecord // 最近的心跳记录，按时间先后排列
+}
+
 // ConnectionManager 连接管理器
 type ConnectionManager struct {
 	client        *client.QQClient
@@ -25,28 +55,51 @@ type ConnectionManager struct {
 	stateMutex    sync.RWMutex
 	eventHandlers []ConnectionEventHandler
 	config        *ConnectionConfig
+	reconnector   Reconnector
+	signMgr       *SignServerManager
+	heartbeat     HeartbeatStats
+	heartbeatMu   sync.RWMutex
+	probe         func() error         // 发送一次心跳包并等待响应
+	pendingProbe  chan heartbeatResult // 已超时但尚未返回的心跳，仅由心跳协程访问
 	stopChan      chan struct{}
+	stopOnce      sync.Once
 	wg            sync.WaitGroup
 }
 
 // ConnectionConfig 连接配置
 type ConnectionConfig struct {
-	AutoReconnect     bool
-	ReconnectInterval time.Duration
-	MaxReconnectTries int
-	HeartbeatInterval time.Duration
+	AutoReconnect        bool
+	ReconnectInterval    time.Duration // 首次重连前的等待时间，之后按指数退避
+	MaxReconnectInterval time.Duration // 退避等待时间上限
+	ReconnectJitter      float64       // 随机抖动比例 (0~1)
+	MaxReconnectTries    int           // 最大重连次数，<=0 表示不限制
+	HeartbeatInterval    time.Duration
+	HeartbeatTimeout     time.Duration // 单次心跳超时
+	MaxHeartbeatFailures int           // 连续失败多少次后判定为断线
 }
 
 // DefaultConnectionConfig 默认连接配置
 func DefaultConnectionConfig() *ConnectionConfig {
 	return &ConnectionConfig{
-		AutoReconnect:     true,
-		ReconnectInterval: 10 * time.Second,
-		MaxReconnectTries: 5,
-		HeartbeatInterval: 30 * time.Second,
+		AutoReconnect:        true,
+		ReconnectInterval:    10 * time.Second,
+		MaxReconnectInterval: 5 * time.Minute,
+		ReconnectJitter:      0.2,
+		MaxReconnectTries:    5,
+		HeartbeatInterval:    30 * time.Second,
+		HeartbeatTimeout:     10 * time.Second,
+		MaxHeartbeatFailures: 3,
 	}
 }
 
+// Reconnector 重连执行器，断线后负责重新建立会话
+type Reconnector interface {
+	Reconnect(ctx context.Context) error
+}
+
+// ErrNoReconnector 未设置重连执行器
+var ErrNoReconnector = errors.New("未设置重连执行器")
+
 // ConnectionEventHandler 连接事件处理器
 type ConnectionEventHandler interface {
 	OnConnected(client *client.QQClient)
@@ -55,15 +108,23 @@ type ConnectionEventHandler interface {
 	OnReconnectFailed(client *client.QQClient, maxAttempts int)
 }
 
+// heartbeatResult 一次心跳的结果
+type heartbeatResult struct {
+	latency time.Duration
+	err     error
+}
+
 // NewConnectionManager 创建新的连接管理器
 func NewConnectionManager(client *client.QQClient) *ConnectionManager {
-	return &ConnectionManager{
+	cm := &ConnectionManager{
 		client:        client,
 		state:         Disconnected,
 		eventHandlers: make([]ConnectionEventHandler, 0),
 		config:        DefaultConnectionConfig(),
 		stopChan:      make(chan struct{}),
 	}
+	cm.probe = cm.sendSsoHeartbeat
+	return cm
 }
 
 // RegisterEventHandler 注册连接事件处理器
@@ -71,6 +132,39 @@ func (cm *ConnectionManager) RegisterEventHandler(handler ConnectionEventHandler
 	cm.eventHandlers = append(cm.eventHandlers, handler)
 }
 
+// SetReconnector 设置重连执行器
+func (cm *ConnectionManager) SetReconnector(reconnector Reconnector) {
+	cm.reconnector = reconnector
+}
+
+// SetConfig 设置连接配置
+func (cm *ConnectionManager) SetConfig(config *ConnectionConfig) {
+	cm.config = config
+}
+
+// GetConfig 获取连接配置
+func (cm *ConnectionManager) GetConfig() *ConnectionConfig {
+	return cm.config
+}
+
+// SetSignServerManager 设置签名服务器管理器
+func (cm *ConnectionManager) SetSignServerManager(signMgr *SignServerManager) {
+	cm.signMgr = signMgr
+}
+
+// GetSignServerManager 获取签名服务器管理器
+func (cm *ConnectionManager) GetSignServerManager() *SignServerManager {
+	return cm.signMgr
+}
+
+// GetActiveSignServer 获取当前使用的签名服务器
+func (cm *ConnectionManager) GetActiveSignServer() string {
+	if cm.signMgr == nil {
+		return ""
+	}
+	return cm.signMgr.GetActiveServer()
+}
+
 // GetState 获取连接状态
 func (cm *ConnectionManager) GetState() ConnectionState {
 	cm.stateMutex.RLock()
@@ -85,6 +179,17 @@ func (cm *ConnectionManager) setState(state ConnectionState) {
 	cm.state = state
 }
 
+// compareAndSetState 仅当当前状态为 from 时设置为 to
+func (cm *ConnectionManager) compareAndSetState(from, to ConnectionState) bool {
+	cm.stateMutex.Lock()
+	defer cm.stateMutex.Unlock()
+	if cm.state != from {
+		return false
+	}
+	cm.state = to
+	return true
+}
+
 // StartMonitoring 开始监控连接
 func (cm *ConnectionManager) StartMonitoring() {
 	cm.setState(Connected)
@@ -104,19 +209,26 @@ func (cm *ConnectionManager) StartMonitoring() {
 	}
 }
 
-// StopMonitoring 停止监控
+// StopMonitoring 停止监控，可重复调用
 func (cm *ConnectionManager) StopMonitoring() {
-	close(cm.stopChan)
+	cm.stopOnce.Do(func() {
+		close(cm.stopChan)
+	})
 	cm.wg.Wait()
 	cm.setState(Disconnected)
 }
 
 // handleDisconnection 处理断开连接
 func (cm *ConnectionManager) handleDisconnection(reason string) {
+	// 重连过程中的断线由重连循环自行处理
+	if cm.GetState() == Reconnecting {
+		return
+	}
+
 	cm.setState(Disconnected)
 	cm.notifyDisconnected(reason)
-	
-	if cm.config.AutoReconnect {
+
+	if cm.config.AutoReconnect && cm.compareAndSetState(Disconnected, Reconnecting) {
 		cm.wg.Add(1)
 		go cm.startReconnect()
 	}
@@ -125,30 +237,93 @@ func (cm *ConnectionManager) handleDisconnection(reason string) {
 // startReconnect 开始重连
 func (cm *ConnectionManager) startReconnect() {
 	defer cm.wg.Done()
-	
-	cm.setState(Reconnecting)
-	
-	for i := 1; i <= cm.config.MaxReconnectTries; i++ {
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
 		select {
 		case <-cm.stopChan:
-			return
-		default:
+			cancel()
+		case <-ctx.Done():
 		}
-		
-		cm.notifyReconnecting(i)
-		utils.Infof("尝试重连 (%d/%d)", i, cm.config.MaxReconnectTries)
-		
-		// 这里应该调用重连逻辑，暂时简化
-		time.Sleep(cm.config.ReconnectInterval)
-		
-		// 模拟重连成功/失败
-		// 实际应该调用登录逻辑
-		if i == cm.config.MaxReconnectTries {
-			cm.setState(Disconnected)
-			cm.notifyReconnectFailed()
-			return
+	}()
+
+	err := cm.reconnectLoop(ctx)
+	if err == nil {
+		cm.heartbeatMu.Lock()
+		cm.heartbeat.Failures = 0
+		cm.heartbeatMu.Unlock()
+
+		cm.setState(Connected)
+		cm.notifyConnected()
+		return
+	}
+
+	cm.setState(Disconnected)
+	if !errors.Is(err, context.Canceled) {
+		utils.Errorf("重连失败: %v", err)
+		cm.notifyReconnectFailed()
+	}
+}
+
+// reconnectLoop 按指数退避反复调用重连执行器，直到成功、取消或次数耗尽
+func (cm *ConnectionManager) reconnectLoop(ctx context.Context) error {
+	if cm.reconnector == nil {
+		return ErrNoReconnector
+	}
+
+	var lastErr error
+	for attempt := 1; cm.config.MaxReconnectTries <= 0 || attempt <= cm.config.MaxReconnectTries; attempt++ {
+		delay := cm.backoff(attempt)
+		utils.Infof("%v 后尝试重连 (%s)", delay, progress(attempt, cm.config.MaxReconnectTries))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		cm.notifyReconnecting(attempt)
+
+		err := cm.reconnector.Reconnect(ctx)
+		if err == nil {
+			utils.Infof("第 %d 次重连成功", attempt)
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		lastErr = err
+		utils.Warnf("第 %d 次重连失败: %v", attempt, err)
+	}
+
+	return lastErr
+}
+
+// progress 格式化当前次数与上限，上限 <=0 表示不限制，此时只显示当前次数
+func progress(n, max int) string {
+	if max <= 0 {
+		return fmt.Sprintf("%d", n)
+	}
+	return fmt.Sprintf("%d/%d", n, max)
+}
+
+// backoff 计算第 attempt 次重连前的等待时间（指数退避 + 随机抖动）
+func (cm *ConnectionManager) backoff(attempt int) time.Duration {
+	delay := cm.config.ReconnectInterval
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if cm.config.MaxReconnectInterval > 0 && delay >= cm.config.MaxReconnectInterval {
+			delay = cm.config.MaxReconnectInterval
+			break
 		}
 	}
+
+	if cm.config.ReconnectJitter > 0 && delay > 0 {
+		delay += time.Duration(rand.Float64() * cm.config.ReconnectJitter * float64(delay))
+	}
+	return delay
 }
 
 // startHeartbeat 启动心跳
@@ -163,15 +338,118 @@ func (cm *ConnectionManager) startHeartbeat() {
 		case <-cm.stopChan:
 			return
 		case <-ticker.C:
-			// 发送心跳包或检查连接状态
-			if cm.GetState() == Connected {
-				utils.Debug("发送心跳包")
-				// 这里可以添加实际的心跳逻辑
+			state := cm.GetState()
+			if state == Connected || state == Degraded {
+				cm.checkHeartbeat()
 			}
 		}
 	}
 }
 
+// checkHeartbeat 发送一次心跳，连续失败时先标记为降级，达到阈值后按断线处理并触发重连
+func (cm *ConnectionManager) checkHeartbeat() {
+	latency, err := cm.sendHeartbeat()
+	failures := cm.recordHeartbeat(latency, err)
+
+	if err == nil {
+		utils.Debugf("心跳延迟 %v", latency)
+		if cm.compareAndSetState(Degraded, Connected) {
+			utils.Info("心跳已恢复，连接恢复正常")
+		}
+		return
+	}
+
+	utils.Warnf("心跳失败 (%s): %v", progress(failures, cm.config.MaxHeartbeatFailures), err)
+	if cm.config.MaxHeartbeatFailures > 0 && failures >= cm.config.MaxHeartbeatFailures {
+		cm.handleDisconnection(fmt.Sprintf("连续 %d 次心跳失败", failures))
+		return
+	}
+	if cm.compareAndSetState(Connected, Degraded) {
+		utils.Warn("连接状态降级")
+	}
+}
+
+// sendHeartbeat 发送心跳包并返回往返延迟
+// 客户端发包无法取消，超时的心跳在后台继续等待响应（最长为客户端的发包超时），
+// 期间不再发送新的心跳，而是继续等待这一次的结果，保证同时最多只有一个心跳在等待
+func (cm *ConnectionManager) sendHeartbeat() (time.Duration, error) {
+	if cm.pendingProbe == nil {
+		result := make(chan heartbeatResult, 1)
+		start := time.Now()
+		go func() {
+			err := cm.probe()
+			result <- heartbeatResult{latency: time.Since(start), err: err}
+		}()
+		cm.pendingProbe = result
+	}
+
+	select {
+	case result := <-cm.pendingProbe:
+		cm.pendingProbe = nil
+		return result.latency, result.err
+	case <-time.After(cm.config.HeartbeatTimeout):
+		return 0, fmt.Errorf("心跳超时 (%v)", cm.config.HeartbeatTimeout)
+	case <-cm.stopChan:
+		return 0, errors.New("连接监控已停止")
+	}
+}
+
+// sendSsoHeartbeat 通过客户端发送一次 SSO 心跳
+func (cm *ConnectionManager) sendSsoHeartbeat() error {
+	if !cm.client.Online.Load() {
+		return errors.New("客户端已离线")
+	}
+	_, err := cm.client.SendSsoPacket(heartbeatCmd, wtlogin.BuildSSOHeartbeatRequest())
+	return err
+}
+
+// recordHeartbeat 记录心跳结果，返回当前连续失败次数
+func (cm *ConnectionManager) recordHeartbeat(latency time.Duration, err error) int {
+	cm.heartbeatMu.Lock()
+	defer cm.heartbeatMu.Unlock()
+
+	record := HeartbeatRecord{Time: time.Now(), Latency: latency}
+	cm.heartbeat.TotalSent++
+	if err != nil {
+		record.Error = err.Error()
+		cm.heartbeat.TotalFailed++
+		cm.heartbeat.Failures++
+	} else {
+		cm.heartbeat.LastSuccess = record.Time
+		cm.heartbeat.LastLatency = latency
+		cm.heartbeat.Failures = 0
+	}
+
+	cm.heartbeat.History = append(cm.heartbeat.History, record)
+	if len(cm.heartbeat.History) > maxHeartbeatHistory {
+		cm.heartbeat.History = cm.heartbeat.History[len(cm.heartbeat.History)-maxHeartbeatHistory:]
+	}
+	return cm.heartbeat.Failures
+}
+
+// GetHeartbeatStats 获取心跳统计
+func (cm *ConnectionManager) GetHeartbeatStats() HeartbeatStats {
+	cm.heartbeatMu.RLock()
+	defer cm.heartbeatMu.RUnlock()
+
+	stats := cm.heartbeat
+	stats.History = make([]HeartbeatRecord, len(cm.heartbeat.History))
+	copy(stats.History, cm.heartbeat.History)
+
+	var total time.Duration
+	var count int
+	for _, record := range stats.History {
+		if record.Error == "" {
+			total += record.Latency
+			count++
+		}
+	}
+	if count > 0 {
+		stats.AvgLatency = total / time.Duration(count)
+	}
+	return stats
+}
+
 // 事件通知方法
 func (cm *ConnectionManager) notifyConnected() {
 	for _, handler := range cm.eventHandlers {
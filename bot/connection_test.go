@@ -0,0 +1,272 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LagrangeDev/LagrangeGo/client"
+)
+
+// fakeReconnector 前 failures 次重连失败，之后成功，记录每次重连时的连接状态
+type fakeReconnector struct {
+	cm       *ConnectionManager
+	failures int
+	calls    int
+	states   []ConnectionState
+	mu       sync.Mutex
+}
+
+func (r *fakeReconnector) Reconnect(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	r.states = append(r.states, r.cm.GetState())
+	if r.calls <= r.failures {
+		return fmt.Errorf("第 %d 次失败", r.calls)
+	}
+	return nil
+}
+
+// recordingHandler 按顺序记录连接事件
+type recordingHandler struct {
+	events []string
+	done   chan struct{}
+	mu     sync.Mutex
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{done: make(chan struct{}, 1)}
+}
+
+func (h *recordingHandler) record(event string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, event)
+}
+
+func (h *recordingHandler) finish() {
+	select {
+	case h.done <- struct{}{}:
+	default:
+	}
+}
+
+func (h *recordingHandler) get() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.events...)
+}
+
+func (h *recordingHandler) OnConnected(client *client.QQClient) {
+	h.record("connected")
+	h.finish()
+}
+
+func (h *recordingHandler) OnDisconnected(client *client.QQClient, reason string) {
+	h.record("disconnected")
+}
+
+func (h *recordingHandler) OnReconnecting(client *client.QQClient, attempt int) {
+	h.record(fmt.Sprintf("reconnecting %d", attempt))
+}
+
+func (h *recordingHandler) OnReconnectFailed(client *client.QQClient, maxAttempts int) {
+	h.record(fmt.Sprintf("failed %d", maxAttempts))
+	h.finish()
+}
+
+// newTestConnectionManager 创建不连接服务器、几乎不等待的连接管理器
+func newTestConnectionManager(maxTries int, failures int) (*ConnectionManager, *fakeReconnector, *recordingHandler) {
+	cm := NewConnectionManager(nil)
+	cm.SetConfig(&ConnectionConfig{
+		AutoReconnect:     true,
+		ReconnectInterval: time.Millisecond,
+		MaxReconnectTries: maxTries,
+	})
+	reconnector := &fakeReconnector{cm: cm, failures: failures}
+	cm.SetReconnector(reconnector)
+	handler := newRecordingHandler()
+	cm.RegisterEventHandler(handler)
+	cm.setState(Connected)
+	return cm, reconnector, handler
+}
+
+func waitHandler(t *testing.T, handler *recordingHandler) {
+	t.Helper()
+	select {
+	case <-handler.done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("reconnect did not finish, events: %v", handler.get())
+	}
+}
+
+func TestReconnectSucceedsAfterFailures(t *testing.T) {
+	cm, reconnector, handler := newTestConnectionManager(5, 2)
+
+	cm.handleDisconnection("test")
+	waitHandler(t, handler)
+	cm.wg.Wait()
+
+	want := []string{"disconnected", "reconnecting 1", "reconnecting 2", "reconnecting 3", "connected"}
+	if got := handler.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if want := []ConnectionState{Reconnecting, Reconnecting, Reconnecting}; !reflect.DeepEqual(reconnector.states, want) {
+		t.Errorf("states during reconnect = %v, want %v", reconnector.states, want)
+	}
+	if state := cm.GetState(); state != Connected {
+		t.Errorf("state = %v, want Connected", state)
+	}
+}
+
+func TestReconnectGivesUp(t *testing.T) {
+	cm, reconnector, handler := newTestConnectionManager(3, 10)
+
+	cm.handleDisconnection("test")
+	waitHandler(t, handler)
+	cm.wg.Wait()
+
+	want := []string{"disconnected", "reconnecting 1", "reconnecting 2", "reconnecting 3", "failed 3"}
+	if got := handler.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+	if reconnector.calls != 3 {
+		t.Errorf("reconnect calls = %d, want 3", reconnector.calls)
+	}
+	if state := cm.GetState(); state != Disconnected {
+		t.Errorf("state = %v, want Disconnected", state)
+	}
+}
+
+func TestReconnectIgnoresDisconnectWhileReconnecting(t *testing.T) {
+	cm, reconnector, handler := newTestConnectionManager(0, 0)
+	cm.setState(Reconnecting)
+
+	cm.handleDisconnection("test")
+	cm.wg.Wait()
+
+	if reconnector.calls != 0 || len(handler.get()) != 0 {
+		t.Errorf("disconnect during reconnect should be ignored, got calls %d events %v", reconnector.calls, handler.get())
+	}
+}
+
+func TestReconnectStopped(t *testing.T) {
+	cm, reconnector, handler := newTestConnectionManager(0, 0)
+	cm.config.ReconnectInterval = time.Hour
+
+	cm.handleDisconnection("test")
+	cm.StopMonitoring()
+
+	if reconnector.calls != 0 {
+		t.Errorf("reconnect should not run after stop, got %d calls", reconnector.calls)
+	}
+	if got := handler.get(); !reflect.DeepEqual(got, []string{"disconnected"}) {
+		t.Errorf("events = %v, want [disconnected]", got)
+	}
+	if state := cm.GetState(); state != Disconnected {
+		t.Errorf("state = %v, want Disconnected", state)
+	}
+
+	// 错误处理路径已经停止监控后，关闭流程再次停止不应 panic
+	cm.StopMonitoring()
+}
+
+func TestReconnectWithoutReconnector(t *testing.T) {
+	cm := NewConnectionManager(nil)
+	if err := cm.reconnectLoop(context.Background()); !errors.Is(err, ErrNoReconnector) {
+		t.Errorf("expected ErrNoReconnector, got %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cm := NewConnectionManager(nil)
+	cm.SetConfig(&ConnectionConfig{
+		ReconnectInterval:    time.Second,
+		MaxReconnectInterval: 5 * time.Second,
+	})
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := cm.backoff(i + 1); got != w {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, w)
+		}
+	}
+
+	cm.config.ReconnectJitter = 0.5
+	for i := 0; i < 20; i++ {
+		if got := cm.backoff(1); got < time.Second || got > 1500*time.Millisecond {
+			t.Fatalf("backoff with jitter = %v, want between 1s and 1.5s", got)
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	if got := progress(2, 5); got != "2/5" {
+		t.Errorf("progress(2, 5) = %q", got)
+	}
+	if got := progress(7, 0); got != "7" {
+		t.Errorf("progress(7, 0) = %q", got)
+	}
+}
+
+func TestHeartbeatTimeoutWaitsForPendingProbe(t *testing.T) {
+	cm := NewConnectionManager(nil)
+	cm.SetConfig(&ConnectionConfig{HeartbeatTimeout: 10 * time.Millisecond})
+	release := make(chan struct{})
+	var mu sync.Mutex
+	probes := 0
+	cm.probe = func() error {
+		mu.Lock()
+		probes++
+		mu.Unlock()
+		<-release
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cm.sendHeartbeat(); err == nil {
+			t.Fatal("expected a heartbeat timeout")
+		}
+	}
+	close(release)
+	if _, err := cm.sendHeartbeat(); err != nil {
+		t.Fatalf("pending heartbeat should succeed, got %v", err)
+	}
+	if _, err := cm.sendHeartbeat(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if probes != 2 {
+		t.Errorf("probes = %d, want 2: a timed-out heartbeat should not start another one", probes)
+	}
+}
+
+func TestHeartbeatFailuresDisconnect(t *testing.T) {
+	cm, _, handler := newTestConnectionManager(0, 0)
+	cm.config.AutoReconnect = false
+	cm.config.HeartbeatTimeout = time.Second
+	cm.config.MaxHeartbeatFailures = 2
+	cm.probe = func() error { return errors.New("offline") }
+
+	cm.checkHeartbeat()
+	if state := cm.GetState(); state != Degraded {
+		t.Errorf("state = %v, want Degraded", state)
+	}
+	cm.checkHeartbeat()
+	if state := cm.GetState(); state != Disconnected {
+		t.Errorf("state = %v, want Disconnected", state)
+	}
+	if got := handler.get(); !reflect.DeepEqual(got, []string{"disconnected"}) {
+		t.Errorf("events = %v, want [disconnected]", got)
+	}
+	if stats := cm.GetHeartbeatStats(); stats.TotalFailed != 2 || len(stats.History) != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
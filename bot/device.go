@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/vintcessun/WE-Assistant/utils"
+	"github.com/LagrangeDev/LagrangeGo/client"
+	"github.com/LagrangeDev/LagrangeGo/client/auth"
+)
+
+// DeviceManager 管理账号的设备信息，首次启动时随机生成并持久化
+type DeviceManager struct {
+	client     *client.QQClient
+	deviceFile string
+	logger     utils.Logger
+}
+
+// NewDeviceManager 创建新的设备信息管理器
+func NewDeviceManager(client *client.QQClient, deviceFile string) *DeviceManager {
+	return &DeviceManager{
+		client:     client,
+		deviceFile: deviceFile,
+		logger:     utils.GetLogger().WithField("module", "device"),
+	}
+}
+
+// LoadDevice 加载设备信息，文件不存在或损坏时生成新的随机设备
+func (dm *DeviceManager) LoadDevice() error {
+	data, err := os.ReadFile(dm.deviceFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			dm.logger.Warnf("读取设备文件失败: %v", err)
+		}
+		return dm.RotateDevice()
+	}
+
+	var device auth.DeviceInfo
+	if err := json.Unmarshal(data, &device); err != nil || device.GUID == "" {
+		dm.logger.Warnf("设备文件 %s 已损坏，重新生成设备信息", dm.deviceFile)
+		return dm.RotateDevice()
+	}
+
+	dm.client.UseDevice(&device)
+	dm.logger.Infof("设备信息加载成功: %s", device.DeviceName)
+	return nil
+}
+
+// RotateDevice 生成新的随机设备信息并覆盖保存
+func (dm *DeviceManager) RotateDevice() error {
+	seed, err := randomSeed()
+	if err != nil {
+		return fmt.Errorf("生成设备信息失败: %w", err)
+	}
+
+	device := auth.NewDeviceInfo(seed)
+	if err := dm.saveDevice(device); err != nil {
+		return err
+	}
+
+	dm.client.UseDevice(device)
+	dm.logger.Infof("已生成新的设备信息: %s", device.DeviceName)
+	return nil
+}
+
+// PinDevice 使用固定种子生成设备信息，相同种子总是得到相同设备
+func (dm *DeviceManager) PinDevice(seed int) error {
+	device := auth.NewDeviceInfo(seed)
+	if err := dm.saveDevice(device); err != nil {
+		return err
+	}
+
+	dm.client.UseDevice(device)
+	dm.logger.Infof("使用固定设备信息: %s", device.DeviceName)
+	return nil
+}
+
+// GetDeviceFile 获取设备文件路径
+func (dm *DeviceManager) GetDeviceFile() string {
+	return dm.deviceFile
+}
+
+// saveDevice 保存设备信息
+func (dm *DeviceManager) saveDevice(device *auth.DeviceInfo) error {
+	data, err := json.MarshalIndent(device, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化设备信息失败: %w", err)
+	}
+
+	if err := os.WriteFile(dm.deviceFile, data, 0600); err != nil {
+		return fmt.Errorf("写入设备文件失败: %w", err)
+	}
+	return nil
+}
+
+// randomSeed 生成随机设备种子
+func randomSeed() (int, error) {
+	var buf [4]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint32(buf[:])), nil
+}
@@ -3,10 +3,13 @@ package bot
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/vintcessun/WE-Assistant/utils"
 	"github.com/LagrangeDev/LagrangeGo/client"
+	"github.com/LagrangeDev/LagrangeGo/client/packets/wtlogin/qrcodestate"
 )
 
 // LoginStrategy 登录策略接口
@@ -15,19 +18,42 @@ type LoginStrategy interface {
 	GetStrategyName() string
 }
 
+// ErrStrategyUnavailable 登录策略当前不可用，无需重试，直接尝试下一个策略
+var ErrStrategyUnavailable = errors.New("登录策略不可用")
+
+// qrCodeLifetime 登录二维码的有效期
+const qrCodeLifetime = 2 * time.Minute
+
 // LoginContext 登录上下文
 type LoginContext struct {
 	MaxRetries int
 	RetryDelay time.Duration
-	Timeout    time.Duration
+	// Timeout 单次登录的总超时时间，不足以刷新 MaxQRCodeRefresh 次二维码时自动延长，参见 EffectiveTimeout
+	Timeout time.Duration
+	// MaxQRCodeRefresh 单次二维码登录中二维码过期后自动刷新的最大次数，<=0 表示不限制
+	MaxQRCodeRefresh int
+}
+
+// EffectiveTimeout 获取实际使用的登录超时时间
+// 限制了二维码刷新次数时，至少留出所有二维码过期所需的时间 ((MaxQRCodeRefresh+1) × 2 分钟)，
+// 避免超时先于刷新次数用完；不限制刷新次数时只受 Timeout 限制
+func (c *LoginContext) EffectiveTimeout() time.Duration {
+	if c.MaxQRCodeRefresh <= 0 {
+		return c.Timeout
+	}
+	if minimum := time.Duration(c.MaxQRCodeRefresh+1) * qrCodeLifetime; c.Timeout < minimum {
+		return minimum
+	}
+	return c.Timeout
 }
 
 // DefaultLoginContext 默认登录上下文
 func DefaultLoginContext() *LoginContext {
 	return &LoginContext{
-		MaxRetries: 3,
-		RetryDelay: 3 * time.Second,
-		Timeout:    5 * time.Minute,
+		MaxRetries:       3,
+		RetryDelay:       3 * time.Second,
+		Timeout:          5 * time.Minute,
+		MaxQRCodeRefresh: 5,
 	}
 }
 
@@ -39,8 +65,8 @@ type LoginManager struct {
 	logger     utils.Logger
 }
 
-// NewLoginManager 创建新的登录管理器
-func NewLoginManager(client *client.QQClient) *LoginManager {
+// NewLoginManager 创建新的登录管理器，password 为空时不启用密码登录
+func NewLoginManager(client *client.QQClient, password string) *LoginManager {
 	lm := &LoginManager{
 		client:  client,
 		context: DefaultLoginContext(),
@@ -49,7 +75,13 @@ func NewLoginManager(client *client.QQClient) *LoginManager {
 
 	// 注册默认登录策略
 	lm.RegisterStrategy(&FastLoginStrategy{logger: lm.logger})
-	lm.RegisterStrategy(&QRCodeLoginStrategy{logger: lm.logger})
+	if password != "" {
+		lm.RegisterStrategy(&PasswordLoginStrategy{
+			verifier: NewTerminalLoginVerifier(),
+			logger:   lm.logger,
+		})
+	}
+	lm.RegisterStrategy(&QRCodeLoginStrategy{maxRefresh: lm.context.MaxQRCodeRefresh, logger: lm.logger})
 
 	return lm
 }
@@ -59,9 +91,65 @@ func (lm *LoginManager) RegisterStrategy(strategy LoginStrategy) {
 	lm.strategies = append(lm.strategies, strategy)
 }
 
+// SetLoginVerifier 设置密码登录使用的验证回调
+func (lm *LoginManager) SetLoginVerifier(verifier LoginVerifier) {
+	for _, strategy := range lm.strategies {
+		if s, ok := strategy.(*PasswordLoginStrategy); ok {
+			s.verifier = verifier
+		}
+	}
+}
+
+// SetLoginContext 设置登录上下文
+func (lm *LoginManager) SetLoginContext(loginContext *LoginContext) {
+	lm.context = loginContext
+	for _, strategy := range lm.strategies {
+		if s, ok := strategy.(*QRCodeLoginStrategy); ok {
+			s.maxRefresh = loginContext.MaxQRCodeRefresh
+		}
+	}
+}
+
+// GetLoginContext 获取登录上下文
+func (lm *LoginManager) GetLoginContext() *LoginContext {
+	return lm.context
+}
+
+// OnQRCodeState 注册扫码状态回调，二维码登录状态每次变化时调用
+func (lm *LoginManager) OnQRCodeState(handler QRCodeStateHandler) {
+	for _, strategy := range lm.strategies {
+		if s, ok := strategy.(*QRCodeLoginStrategy); ok {
+			s.stateHandlers = append(s.stateHandlers, handler)
+		}
+	}
+}
+
+// SetQRCodeProcessor 设置终端二维码处理器
+func (lm *LoginManager) SetQRCodeProcessor(processor *QRCodeProcessor) {
+	for _, strategy := range lm.strategies {
+		if s, ok := strategy.(*QRCodeLoginStrategy); ok {
+			s.qrProcessor = processor
+		}
+	}
+}
+
+// SetQRCodeWebServer 设置二维码登录网页，二维码会同时发布到网页上
+func (lm *LoginManager) SetQRCodeWebServer(server *QRCodeWebServer) {
+	for _, strategy := range lm.strategies {
+		if s, ok := strategy.(*QRCodeLoginStrategy); ok {
+			s.webServer = server
+		}
+	}
+}
+
 // Login 执行登录
 func (lm *LoginManager) Login() error {
-	ctx, cancel := context.WithTimeout(context.Background(), lm.context.Timeout)
+	return lm.LoginWithContext(context.Background())
+}
+
+// LoginWithContext 在给定上下文中依次尝试各登录策略
+func (lm *LoginManager) LoginWithContext(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, lm.context.EffectiveTimeout())
 	defer cancel()
 
 	for _, strategy := range lm.strategies {
@@ -76,9 +164,20 @@ func (lm *LoginManager) Login() error {
 		lm.logger.Warnf("使用 %s 登录失败: %v", strategy.GetStrategyName(), err)
 	}
 
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
 	return errors.New("所有登录策略都失败了")
 }
 
+// Reconnect 断线后重新登录，实现 Reconnector 接口
+func (lm *LoginManager) Reconnect(ctx context.Context) error {
+	if lm.client.Online.Load() {
+		lm.client.Disconnect()
+	}
+	return lm.LoginWithContext(ctx)
+}
+
 // tryLoginWithRetry 带重试的登录尝试
 func (lm *LoginManager) tryLoginWithRetry(ctx context.Context, strategy LoginStrategy) error {
 	var lastErr error
@@ -94,11 +193,19 @@ func (lm *LoginManager) tryLoginWithRetry(ctx context.Context, strategy LoginStr
 		if err == nil {
 			return nil
 		}
+		// 策略不可用或用户主动取消时重试没有意义
+		if errors.Is(err, ErrStrategyUnavailable) || errors.Is(err, ErrQRCodeCanceled) {
+			return err
+		}
 
 		lastErr = err
 		if i < lm.context.MaxRetries-1 {
 			lm.logger.Warnf("第 %d 次尝试失败: %v，%v 后重试", i+1, err, lm.context.RetryDelay)
-			time.Sleep(lm.context.RetryDelay)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(lm.context.RetryDelay):
+			}
 		}
 	}
 
@@ -115,18 +222,111 @@ func (s *FastLoginStrategy) GetStrategyName() string {
 }
 
 func (s *FastLoginStrategy) Login(ctx context.Context, client *client.QQClient) error {
-	sig := client.Sig()
-	if sig == nil {
-		return errors.New("没有可用的签名信息")
+	if !hasLoginCache(client.Sig()) {
+		return fmt.Errorf("%w: 没有可用的签名信息", ErrStrategyUnavailable)
 	}
 
 	return client.FastLogin()
 }
 
+// PasswordLoginStrategy 密码登录策略
+type PasswordLoginStrategy struct {
+	verifier LoginVerifier
+	logger   utils.Logger
+}
+
+// maxCaptchaAttempts 单次密码登录中允许提交验证码的最大次数
+const maxCaptchaAttempts = 3
+
+func (s *PasswordLoginStrategy) GetStrategyName() string {
+	return "密码登录"
+}
+
+func (s *PasswordLoginStrategy) Login(ctx context.Context, qqClient *client.QQClient) error {
+	resp, err := qqClient.PasswordLogin()
+	if resp == nil {
+		return err
+	}
+
+	for captcha := 0; ; captcha++ {
+		if resp.Success {
+			return nil
+		}
+
+		switch resp.Error {
+		case client.SliderNeededError:
+			if captcha >= maxCaptchaAttempts {
+				return errors.New("滑块验证失败次数过多")
+			}
+			if s.verifier == nil {
+				return errors.New("需要滑块验证，但未设置验证回调")
+			}
+
+			s.logger.Warnf("登录需要滑块验证: %s", resp.VerifyURL)
+			ticket, randStr, verifyErr := s.verifier.OnSlider(ctx, resp.VerifyURL)
+			if verifyErr != nil {
+				return fmt.Errorf("滑块验证失败: %w", verifyErr)
+			}
+
+			resp, err = qqClient.SubmitCaptcha(ticket, randStr, captchaAid(resp.VerifyURL))
+			if resp == nil {
+				return fmt.Errorf("提交验证码失败: %w", err)
+			}
+
+		case client.UnsafeDeviceError:
+			if s.verifier == nil {
+				return errors.New("需要新设备验证，但未设置验证回调")
+			}
+
+			s.logger.Warn("登录需要新设备验证")
+			verifyURL, err := qqClient.GetNewDeviceVerifyURL()
+			if err != nil {
+				return fmt.Errorf("获取新设备验证链接失败: %w", err)
+			}
+			if err := s.verifier.OnNewDevice(ctx, verifyURL); err != nil {
+				return fmt.Errorf("新设备验证失败: %w", err)
+			}
+			if err := qqClient.NewDeviceVerify(verifyURL); err != nil {
+				return fmt.Errorf("新设备验证失败: %w", err)
+			}
+			return nil
+
+		default:
+			if err != nil {
+				return fmt.Errorf("密码登录失败 (%s): %w", resp.ErrorMessage, err)
+			}
+			return fmt.Errorf("密码登录失败: %s", resp.ErrorMessage)
+		}
+	}
+}
+
+// captchaAid 从滑块验证链接中解析 aid (sid 参数)
+func captchaAid(verifyURL string) string {
+	u, err := url.Parse(verifyURL)
+	if err != nil {
+		return ""
+	}
+	return u.Query().Get("sid")
+}
+
+var (
+	// ErrQRCodeExpired 二维码已过期，且自动刷新次数已用完
+	ErrQRCodeExpired = errors.New("二维码已过期")
+	// ErrQRCodeCanceled 用户在手机上取消了登录
+	ErrQRCodeCanceled = errors.New("用户已在手机上取消登录")
+)
+
+// QRCodeStateHandler 扫码状态回调
+type QRCodeStateHandler func(state QRCodeState)
+
 // QRCodeLoginStrategy 二维码登录策略
 type QRCodeLoginStrategy struct {
-	qrProcessor *QRCodeProcessor
-	logger      utils.Logger
+	qrProcessor   *QRCodeProcessor
+	webServer     *QRCodeWebServer
+	stateHandlers []QRCodeStateHandler
+	state         QRCodeState
+	maxRefresh    int
+	logger        utils.Logger
 }
 
 func (s *QRCodeLoginStrategy) GetStrategyName() string {
@@ -137,21 +337,39 @@ func (s *QRCodeLoginStrategy) Login(ctx context.Context, client *client.QQClient
 	if s.qrProcessor == nil {
 		s.qrProcessor = NewQRCodeProcessor()
 	}
-
-	// 获取二维码
-	png, _, err := client.FetchQRCodeDefault()
-	if err != nil {
-		return err
+	if s.webServer != nil {
+		if err := s.webServer.Start(); err != nil {
+			s.logger.Warnf("二维码登录网页启动失败: %v", err)
+		}
 	}
 
-	// 显示二维码
-	err = s.qrProcessor.DisplayQRCode(png)
-	if err != nil {
-		s.logger.Warnf("二维码显示失败: %v", err)
-	}
+	for refresh := 0; ; refresh++ {
+		// 获取二维码
+		png, _, err := client.FetchQRCodeDefault()
+		if err != nil {
+			return err
+		}
 
-	// 轮询登录状态
-	return s.pollLoginStatus(ctx, client)
+		// 显示二维码
+		err = s.qrProcessor.DisplayQRCode(png)
+		if err != nil {
+			s.logger.Warnf("二维码显示失败: %v", err)
+		}
+		if s.webServer != nil {
+			s.webServer.SetQRCode(png)
+		}
+		s.setState(QRCodeStateWaiting)
+
+		// 轮询登录状态，二维码过期时重新获取
+		err = s.pollLoginStatus(ctx, client)
+		if !errors.Is(err, ErrQRCodeExpired) {
+			return err
+		}
+		if s.maxRefresh > 0 && refresh >= s.maxRefresh {
+			return fmt.Errorf("%w: 已自动刷新 %d 次", ErrQRCodeExpired, refresh)
+		}
+		s.logger.Infof("二维码已过期，正在重新获取 (%d)", refresh+1)
+	}
 }
 
 // pollLoginStatus 轮询登录状态
@@ -169,15 +387,40 @@ func (s *QRCodeLoginStrategy) pollLoginStatus(ctx context.Context, client *clien
 				return err
 			}
 
-			if !retCode.Waitable() {
-				if !retCode.Success() {
-					return errors.New(retCode.Name())
-				}
-
+			switch retCode {
+			case qrcodestate.WaitingForScan:
+				s.setState(QRCodeStateWaiting)
+			case qrcodestate.WaitingForConfirm:
+				s.setState(QRCodeStateScanned)
+			case qrcodestate.Expired:
+				s.setState(QRCodeStateExpired)
+				return ErrQRCodeExpired
+			case qrcodestate.Canceled:
+				s.setState(QRCodeStateCanceled)
+				return ErrQRCodeCanceled
+			case qrcodestate.Confirmed:
 				// 执行二维码登录
+				s.setState(QRCodeStateConfirmed)
 				_, err = client.QRCodeLogin()
 				return err
+			default:
+				return fmt.Errorf("未知的扫码状态: %s (%d)", retCode.Name(), retCode)
 			}
 		}
 	}
 }
+
+// setState 记录扫码状态，状态变化时同步到登录网页并通知回调
+func (s *QRCodeLoginStrategy) setState(state QRCodeState) {
+	if s.state == state {
+		return
+	}
+	s.state = state
+
+	if s.webServer != nil {
+		s.webServer.SetState(state)
+	}
+	for _, handler := range s.stateHandlers {
+		handler(state)
+	}
+}
@@ -0,0 +1,88 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LagrangeDev/LagrangeGo/client"
+)
+
+// fakeLoginStrategy 依次返回 errs 中的错误，用完后返回 nil
+type fakeLoginStrategy struct {
+	errs  []error
+	calls int
+}
+
+func (s *fakeLoginStrategy) GetStrategyName() string {
+	return "测试登录"
+}
+
+func (s *fakeLoginStrategy) Login(ctx context.Context, client *client.QQClient) error {
+	s.calls++
+	if s.calls <= len(s.errs) {
+		return s.errs[s.calls-1]
+	}
+	return nil
+}
+
+func newTestLoginManager() *LoginManager {
+	lm := NewLoginManager(nil, "")
+	lm.SetLoginContext(&LoginContext{MaxRetries: 3, Timeout: time.Second})
+	return lm
+}
+
+func TestTryLoginWithRetry(t *testing.T) {
+	lm := newTestLoginManager()
+	strategy := &fakeLoginStrategy{errs: []error{errors.New("a"), errors.New("b")}}
+
+	if err := lm.tryLoginWithRetry(context.Background(), strategy); err != nil {
+		t.Fatal(err)
+	}
+	if strategy.calls != 3 {
+		t.Errorf("calls = %d, want 3", strategy.calls)
+	}
+}
+
+func TestTryLoginWithRetryStopsEarly(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"unavailable", ErrStrategyUnavailable},
+		{"canceled", ErrQRCodeCanceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lm := newTestLoginManager()
+			strategy := &fakeLoginStrategy{errs: []error{tt.err, tt.err, tt.err}}
+
+			if err := lm.tryLoginWithRetry(context.Background(), strategy); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+			if strategy.calls != 1 {
+				t.Errorf("calls = %d, want 1", strategy.calls)
+			}
+		})
+	}
+}
+
+func TestEffectiveTimeout(t *testing.T) {
+	tests := []struct {
+		timeout    time.Duration
+		maxRefresh int
+		want       time.Duration
+	}{
+		{5 * time.Minute, 5, 12 * time.Minute},
+		{30 * time.Minute, 5, 30 * time.Minute},
+		{5 * time.Minute, 0, 5 * time.Minute},
+		{5 * time.Minute, -1, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		c := &LoginContext{Timeout: tt.timeout, MaxQRCodeRefresh: tt.maxRefresh}
+		if got := c.EffectiveTimeout(); got != tt.want {
+			t.Errorf("EffectiveTimeout(%v, %d) = %v, want %v", tt.timeout, tt.maxRefresh, got, tt.want)
+		}
+	}
+}
@@ -2,8 +2,10 @@ package bot
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"io"
+	"strings"
 
 	"github.com/mdp/qrterminal/v3"
 	"github.com/tuotoo/qrcode"
@@ -36,6 +38,22 @@ func DefaultQRCodeConfig() *QRCodeConfig {
 	}
 }
 
+// ParseQRCodeLevel 解析二维码纠错等级: L, M, Q, H
+func ParseQRCodeLevel(level string) (qr.Level, error) {
+	switch strings.ToUpper(level) {
+	case "L":
+		return qr.L, nil
+	case "M", "":
+		return qr.M, nil
+	case "Q":
+		return qr.Q, nil
+	case "H":
+		return qr.H, nil
+	default:
+		return qr.M, fmt.Errorf("未知的二维码纠错等级: %s", level)
+	}
+}
+
 // NewQRCodeProcessor 创建新的二维码处理器
 func NewQRCodeProcessor() *QRCodeProcessor {
 	return &QRCodeProcessor{
@@ -60,7 +78,12 @@ func (qp *QRCodeProcessor) DisplayQRCode(pngData []byte) error {
 	
 	logrus.Infoln("请使用手机扫码登录：")
 	
-	// 生成终端二维码
+	qp.DisplayContent(qrMatrix.Content)
+	return nil
+}
+
+// DisplayContent 将文本内容（如验证链接）以二维码形式输出到终端
+func (qp *QRCodeProcessor) DisplayContent(content string) {
 	config := qrterminal.Config{
 		Level:     qp.config.Level,
 		Writer:    qp.config.Writer,
@@ -68,9 +91,8 @@ func (qp *QRCodeProcessor) DisplayQRCode(pngData []byte) error {
 		WhiteChar: qp.config.WhiteChar,
 		QuietZone: qp.config.QuietZone,
 	}
-	
-	qrterminal.GenerateWithConfig(qrMatrix.Content, config)
-	return nil
+
+	qrterminal.GenerateWithConfig(content, config)
 }
 
 // SaveQRCodeToFile 保存二维码到文件
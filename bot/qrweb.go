@@ -0,0 +1,238 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/vintcessun/WE-Assistant/utils"
+)
+
+// QRCodeState 二维码扫码状态
+type QRCodeState string
+
+const (
+	QRCodeStateWaiting   QRCodeState = "waiting"   // 等待扫码
+	QRCodeStateScanned   QRCodeState = "scanned"   // 已扫码，等待手机确认
+	QRCodeStateConfirmed QRCodeState = "confirmed" // 已确认登录
+	QRCodeStateExpired   QRCodeState = "expired"   // 二维码已过期
+	QRCodeStateCanceled  QRCodeState = "canceled"  // 已在手机上取消登录
+)
+
+// QRCodeWebServer 二维码登录网页，在无法查看终端输出时（容器、systemd）通过浏览器扫码
+// 扫码者即登录该账号，因此默认只监听本机，且页面地址中带有每次启动随机生成的令牌
+type QRCodeWebServer struct {
+	addr      string
+	token     string
+	server    *http.Server
+	png       []byte
+	state     QRCodeState
+	updatedAt time.Time
+	mu        sync.RWMutex
+	logger    utils.Logger
+}
+
+// NewQRCodeWebServer 创建二维码登录网页服务器，addr 为监听地址，如 "127.0.0.1:8081"
+// 只填写端口（如 ":8081"）时监听 127.0.0.1
+func NewQRCodeWebServer(addr string) *QRCodeWebServer {
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		addr = net.JoinHostPort("127.0.0.1", port)
+	}
+	return &QRCodeWebServer{
+		addr:   addr,
+		token:  newQRCodeToken(),
+		state:  QRCodeStateWaiting,
+		logger: utils.GetLogger().WithField("module", "qrweb"),
+	}
+}
+
+// newQRCodeToken 生成页面地址中的随机令牌
+func newQRCodeToken() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// URLPath 获取页面路径，包含随机令牌，如 /<令牌>/
+func (qs *QRCodeWebServer) URLPath() string {
+	return "/" + qs.token + "/"
+}
+
+// Start 开始监听，重复调用不会重复启动
+func (qs *QRCodeWebServer) Start() error {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	if qs.server != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", qs.addr)
+	if err != nil {
+		return err
+	}
+
+	// 所有页面都在 /<令牌>/ 之下，其他路径一律返回 404
+	pages := http.NewServeMux()
+	pages.HandleFunc("/", qs.handleIndex)
+	pages.HandleFunc("/qrcode.png", qs.handleQRCode)
+	pages.HandleFunc("/status", qs.handleStatus)
+	mux := http.NewServeMux()
+	mux.Handle(qs.URLPath(), http.StripPrefix("/"+qs.token, pages))
+	qs.server = &http.Server{Handler: mux}
+
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			qs.logger.Errorf("二维码登录网页异常退出: %v", err)
+		}
+	}(qs.server)
+
+	qs.logger.Infof("二维码登录网页已启动: http://%s%s", listener.Addr(), qs.URLPath())
+	return nil
+}
+
+// Stop 停止监听
+func (qs *QRCodeWebServer) Stop() {
+	qs.mu.Lock()
+	server := qs.server
+	qs.server = nil
+	qs.mu.Unlock()
+
+	if server == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		qs.logger.Warnf("关闭二维码登录网页失败: %v", err)
+	}
+}
+
+// SetQRCode 更新当前的二维码图片，状态重置为等待扫码
+func (qs *QRCodeWebServer) SetQRCode(png []byte) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	qs.png = png
+	qs.state = QRCodeStateWaiting
+	qs.updatedAt = time.Now()
+}
+
+// SetState 更新扫码状态
+func (qs *QRCodeWebServer) SetState(state QRCodeState) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	if qs.state == state {
+		return
+	}
+	qs.state = state
+	qs.updatedAt = time.Now()
+}
+
+// GetState 获取当前扫码状态
+func (qs *QRCodeWebServer) GetState() QRCodeState {
+	qs.mu.RLock()
+	defer qs.mu.RUnlock()
+	return qs.state
+}
+
+// handleQRCode 输出当前二维码 PNG
+func (qs *QRCodeWebServer) handleQRCode(w http.ResponseWriter, r *http.Request) {
+	qs.mu.RLock()
+	png := qs.png
+	qs.mu.RUnlock()
+
+	if png == nil {
+		http.Error(w, "二维码尚未生成", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(png)
+}
+
+// handleStatus 以 JSON 输出当前扫码状态
+func (qs *QRCodeWebServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	qs.mu.RLock()
+	status := struct {
+		State     QRCodeState `json:"state"`
+		Ready     bool        `json:"ready"`
+		UpdatedAt int64       `json:"updatedAt"`
+	}{
+		State:     qs.state,
+		Ready:     qs.png != nil,
+		UpdatedAt: qs.updatedAt.UnixMilli(),
+	}
+	qs.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleIndex 输出状态页面
+func (qs *QRCodeWebServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(qrcodeIndexHTML))
+}
+
+const qrcodeIndexHTML = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>扫码登录</title>
+<style>
+body { font-family: sans-serif; display: flex; flex-direction: column; align-items: center; margin-top: 10vh; color: #333; }
+img { width: 240px; height: 240px; image-rendering: pixelated; border: 1px solid #ddd; }
+#state { margin-top: 16px; font-size: 18px; }
+</style>
+</head>
+<body>
+<h2>请使用手机 QQ 扫码登录</h2>
+<img id="qrcode" alt="二维码">
+<div id="state">正在获取二维码...</div>
+<script>
+const texts = {
+  waiting: "等待扫码",
+  scanned: "已扫码，请在手机上确认",
+  confirmed: "登录成功",
+  expired: "二维码已过期，正在刷新...",
+  canceled: "已在手机上取消登录"
+};
+let version = 0;
+let shown = false;
+async function poll() {
+  try {
+    const resp = await fetch("status", { cache: "no-store" });
+    const status = await resp.json();
+    document.getElementById("state").textContent = texts[status.state] || status.state;
+    if (status.ready && (!shown || (status.state === "waiting" && status.updatedAt !== version))) {
+      document.getElementById("qrcode").src = "qrcode.png?t=" + status.updatedAt;
+      shown = true;
+    }
+    version = status.updatedAt;
+  } catch (e) {
+    document.getElementById("state").textContent = "无法连接到服务";
+  }
+  setTimeout(poll, 2000);
+}
+poll();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,217 @@
+package bot
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+)
+
+// 签名文件格式:
+//
+//	magic(4) | version(1) | flags(1) | crc32(4) | body
+//
+// flags 置位 sigFlagEncrypted 时 body 为 nonce(12) + AES-256-GCM 密文，
+// crc32 始终针对写入磁盘的 body 计算，用于在解密/反序列化之前发现文件损坏
+const (
+	sigFileMagic     = "WESG"
+	sigFileVersion   = 1
+	sigFlagEncrypted = 1 << 0
+	sigHeaderSize    = len(sigFileMagic) + 1 + 1 + 4
+)
+
+// SigKeyEnv 签名文件加密密钥的环境变量名
+const SigKeyEnv = "WEA_SIG_KEY"
+
+var (
+	// ErrSigCorrupted 签名文件已损坏
+	ErrSigCorrupted = errors.New("签名文件已损坏")
+	// ErrSigKeyRequired 签名文件已加密但未提供密钥
+	ErrSigKeyRequired = errors.New("签名文件已加密，但未提供密钥")
+	// ErrSigUnsupportedVersion 不支持的签名文件版本
+	ErrSigUnsupportedVersion = errors.New("不支持的签名文件版本")
+)
+
+// LoadSigKey 加载签名文件加密密钥，优先使用环境变量，其次使用密钥文件
+// 两者均未配置时返回 nil，表示不加密
+func LoadSigKey(keyFile string) ([]byte, error) {
+	if key := os.Getenv(SigKeyEnv); key != "" {
+		return deriveSigKey([]byte(key)), nil
+	}
+	if keyFile == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥文件失败: %w", err)
+	}
+	key := bytes.TrimSpace(data)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("密钥文件 %s 为空", keyFile)
+	}
+	return deriveSigKey(key), nil
+}
+
+// deriveSigKey 将任意长度的密钥材料派生为 AES-256 密钥
+func deriveSigKey(material []byte) []byte {
+	sum := sha256.Sum256(material)
+	return sum[:]
+}
+
+// encodeSigFile 将序列化后的签名编码为签名文件内容
+func encodeSigFile(sig []byte, key []byte) ([]byte, error) {
+	body := sig
+	var flags byte
+	if key != nil {
+		encrypted, err := encryptSig(sig, key)
+		if err != nil {
+			return nil, err
+		}
+		body = encrypted
+		flags |= sigFlagEncrypted
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, sigHeaderSize+len(body)))
+	buf.WriteString(sigFileMagic)
+	buf.WriteByte(sigFileVersion)
+	buf.WriteByte(flags)
+	_ = binary.Write(buf, binary.BigEndian, crc32.ChecksumIEEE(body))
+	buf.Write(body)
+	return buf.Bytes(), nil
+}
+
+// decodeSigFile 解析签名文件内容，返回序列化的签名
+// 不带文件头的旧格式签名文件原样返回，由调用方校验
+func decodeSigFile(data []byte, key []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(sigFileMagic)) {
+		return data, nil
+	}
+	if len(data) < sigHeaderSize {
+		return nil, fmt.Errorf("%w: 文件头不完整", ErrSigCorrupted)
+	}
+
+	version := data[len(sigFileMagic)]
+	flags := data[len(sigFileMagic)+1]
+	checksum := binary.BigEndian.Uint32(data[len(sigFileMagic)+2 : sigHeaderSize])
+	body := data[sigHeaderSize:]
+
+	if version != sigFileVersion {
+		return nil, fmt.Errorf("%w: %d", ErrSigUnsupportedVersion, version)
+	}
+	if crc32.ChecksumIEEE(body) != checksum {
+		return nil, fmt.Errorf("%w: 校验和不匹配", ErrSigCorrupted)
+	}
+
+	if flags&sigFlagEncrypted == 0 {
+		return body, nil
+	}
+	if key == nil {
+		return nil, ErrSigKeyRequired
+	}
+	return decryptSig(body, key)
+}
+
+// encryptSig 使用 AES-256-GCM 加密签名
+func encryptSig(plain []byte, key []byte) ([]byte, error) {
+	gcm, err := newSigCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plain, []byte(sigFileMagic)), nil
+}
+
+// decryptSig 解密签名
+func decryptSig(body []byte, key []byte) ([]byte, error) {
+	gcm, err := newSigCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: 密文过短", ErrSigCorrupted)
+	}
+	nonce, ciphertext := body[:gcm.NonceSize()], body[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, []byte(sigFileMagic))
+	if err != nil {
+		return nil, fmt.Errorf("%w: 解密失败，密钥错误或文件被篡改", ErrSigCorrupted)
+	}
+	return plain, nil
+}
+
+// newSigCipher 创建 AES-GCM 实例
+func newSigCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("创建加密器失败: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// writeFileAtomic 先写入临时文件再重命名，避免写入中断导致文件损坏
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmpName, err := writeTempFile(filename, data, perm)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, filename); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+	return nil
+}
+
+// writeTempFile 在目标文件所在目录写入并同步临时文件，返回临时文件路径
+func writeTempFile(filename string, data []byte, perm os.FileMode) (tmpName string, err error) {
+	dir := filepath.Dir(filename)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(filename)+".tmp*")
+	if err != nil {
+		return "", err
+	}
+	tmpName = tmp.Name()
+	defer func() {
+		if err != nil {
+			os.Remove(tmpName)
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+	if err = os.Chmod(tmpName, perm); err != nil {
+		return "", err
+	}
+	return tmpName, nil
+}
+
+// linkOrCopy 为文件创建硬链接，文件系统不支持时复制文件内容
+func linkOrCopy(src, dst string) error {
+	if err := os.Link(src, dst); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0600)
+}
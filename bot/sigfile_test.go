@@ -0,0 +1,96 @@
+package bot
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSigFileRoundTrip(t *testing.T) {
+	sig := []byte("serialized sig")
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{"plain", nil},
+		{"encrypted", deriveSigKey([]byte("secret"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := encodeSigFile(sig, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if encrypted := bytes.Contains(data, sig); encrypted == (tt.key != nil) {
+				t.Errorf("sig stored in plain text = %v, key = %v", encrypted, tt.key != nil)
+			}
+
+			got, err := decodeSigFile(data, tt.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, sig) {
+				t.Errorf("decoded %q, want %q", got, sig)
+			}
+		})
+	}
+}
+
+func TestSigFileErrors(t *testing.T) {
+	key := deriveSigKey([]byte("secret"))
+	encrypted, err := encodeSigFile([]byte("serialized sig"), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupted := append([]byte(nil), encrypted...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	unsupported := append([]byte(nil), encrypted...)
+	unsupported[len(sigFileMagic)] = sigFileVersion + 1
+
+	tests := []struct {
+		name string
+		data []byte
+		key  []byte
+		want error
+	}{
+		{"crc mismatch", corrupted, key, ErrSigCorrupted},
+		{"wrong key", encrypted, deriveSigKey([]byte("other")), ErrSigCorrupted},
+		{"missing key", encrypted, nil, ErrSigKeyRequired},
+		{"truncated header", encrypted[:sigHeaderSize-1], key, ErrSigCorrupted},
+		{"unsupported version", unsupported, key, ErrSigUnsupportedVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeSigFile(tt.data, tt.key); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestSigFileLegacyFormat(t *testing.T) {
+	legacy := []byte("legacy sig without header")
+	got, err := decodeSigFile(legacy, deriveSigKey([]byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, legacy) {
+		t.Errorf("decoded %q, want the legacy data unchanged", got)
+	}
+}
+
+func TestLoadSigKey(t *testing.T) {
+	t.Setenv(SigKeyEnv, "")
+	if key, err := LoadSigKey(""); err != nil || key != nil {
+		t.Errorf("no key configured: got %v, %v", key, err)
+	}
+
+	t.Setenv(SigKeyEnv, "secret")
+	key, err := LoadSigKey("missing.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, deriveSigKey([]byte("secret"))) {
+		t.Error("environment key should take precedence over the key file")
+	}
+}
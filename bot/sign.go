@@ -0,0 +1,411 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/vintcessun/WE-Assistant/config"
+	"github.com/vintcessun/WE-Assistant/utils"
+	"github.com/LagrangeDev/LagrangeGo/client/auth"
+	"github.com/LagrangeDev/LagrangeGo/client/sign"
+)
+
+const (
+	defaultPlatform   = "linux"
+	defaultAppVersion = "3.2.15-30366"
+)
+
+// ResolveAppInfo 根据平台与版本号获取协议信息，为空时使用默认值
+func ResolveAppInfo(platform, version string) (*auth.AppInfo, error) {
+	if platform == "" {
+		platform = defaultPlatform
+	}
+	if version == "" {
+		version = defaultAppVersion
+	}
+
+	versions, ok := auth.AppList[platform]
+	if !ok {
+		return nil, fmt.Errorf("不支持的协议平台: %s", platform)
+	}
+	appInfo, ok := versions[version]
+	if !ok {
+		return nil, fmt.Errorf("平台 %s 不支持协议版本: %s", platform, version)
+	}
+	return appInfo, nil
+}
+
+// SignConfig 签名服务器配置
+type SignConfig struct {
+	Timeout             time.Duration // 单次签名请求超时
+	HealthCheckInterval time.Duration // 健康检查间隔
+	MaxFailures         int           // 连续失败多少次后判定为不可用
+}
+
+// DefaultSignConfig 默认签名服务器配置
+func DefaultSignConfig() *SignConfig {
+	return &SignConfig{
+		Timeout:             8 * time.Second,
+		HealthCheckInterval: 5 * time.Minute,
+		MaxFailures:         1,
+	}
+}
+
+// SignServerStatus 签名服务器状态
+type SignServerStatus struct {
+	URL       string
+	Priority  int
+	Healthy   bool
+	Failures  int
+	Latency   time.Duration
+	LastError string
+	LastCheck time.Time
+}
+
+// SignServerManager 签名服务器管理器，按优先级选择可用服务器并在失败时自动切换
+// 实现 sign.Provider 接口
+type SignServerManager struct {
+	servers    []*SignServerStatus
+	active     string
+	app        *auth.AppInfo
+	headers    http.Header
+	httpClient *http.Client
+	config     *SignConfig
+	mu         sync.RWMutex
+	logger     utils.Logger
+	stopChan   chan struct{}
+	stopOnce   sync.Once
+}
+
+// NewSignServerManager 创建新的签名服务器管理器并启动健康检查
+func NewSignServerManager(servers []config.SignServerConfig, signConfig *SignConfig) *SignServerManager {
+	if signConfig == nil {
+		signConfig = DefaultSignConfig()
+	}
+
+	sm := &SignServerManager{
+		headers:    http.Header{},
+		httpClient: &http.Client{},
+		config:     signConfig,
+		logger:     utils.GetLogger().WithField("module", "sign"),
+		stopChan:   make(chan struct{}),
+	}
+	for _, server := range servers {
+		sm.addServer(server.URL, server.Priority)
+	}
+
+	if sm.config.HealthCheckInterval > 0 {
+		go sm.healthCheckLoop()
+	}
+	return sm
+}
+
+// addServer 添加签名服务器并按优先级排序（数值越小越优先）
+func (sm *SignServerManager) addServer(serverURL string, priority int) {
+	if serverURL == "" {
+		return
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.servers = append(sm.servers, &SignServerStatus{
+		URL:      serverURL,
+		Priority: priority,
+		Healthy:  true,
+	})
+	sort.SliceStable(sm.servers, func(i, j int) bool {
+		return sm.servers[i].Priority < sm.servers[j].Priority
+	})
+}
+
+// Sign 依次尝试可用的签名服务器
+func (sm *SignServerManager) Sign(cmd string, seq uint32, data []byte) (*sign.Response, error) {
+	if !sign.ContainSignPKG(cmd) {
+		return nil, nil
+	}
+
+	candidates := sm.candidates()
+	if len(candidates) == 0 {
+		return nil, errors.New("没有可用的签名服务器")
+	}
+
+	var lastErr error
+	for _, server := range candidates {
+		start := time.Now()
+		resp, err := sm.request(server.URL, cmd, seq, data)
+		if errors.Is(err, sign.ErrVersionMismatch) {
+			// 服务器可用但协议版本不一致，切换服务器无法解决，直接返回
+			return nil, err
+		}
+		if err != nil {
+			lastErr = err
+			sm.markFailure(server, err)
+			continue
+		}
+
+		sm.markSuccess(server, time.Since(start))
+		return resp, nil
+	}
+
+	return nil, fmt.Errorf("所有签名服务器均不可用: %w", lastErr)
+}
+
+// candidates 获取本次签名的候选服务器，健康的在前，不健康的作为最后手段
+func (sm *SignServerManager) candidates() []*SignServerStatus {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	healthy := make([]*SignServerStatus, 0, len(sm.servers))
+	unhealthy := make([]*SignServerStatus, 0)
+	for _, server := range sm.servers {
+		if server.Healthy {
+			healthy = append(healthy, server)
+		} else {
+			unhealthy = append(unhealthy, server)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+// markSuccess 记录签名成功，必要时切换当前服务器
+func (sm *SignServerManager) markSuccess(server *SignServerStatus, latency time.Duration) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	server.Healthy = true
+	server.Failures = 0
+	server.Latency = latency
+	server.LastError = ""
+	server.LastCheck = time.Now()
+
+	if sm.active != server.URL {
+		if sm.active != "" {
+			sm.logger.Warnf("签名服务器已切换: %s -> %s", sm.active, server.URL)
+		}
+		sm.active = server.URL
+	}
+}
+
+// markFailure 记录签名失败，连续失败达到阈值后标记为不可用
+func (sm *SignServerManager) markFailure(server *SignServerStatus, err error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	server.Failures++
+	server.LastError = err.Error()
+	server.LastCheck = time.Now()
+	if server.Healthy && server.Failures >= sm.config.MaxFailures {
+		server.Healthy = false
+		sm.logger.Warnf("签名服务器 %s 不可用: %v", server.URL, err)
+	}
+}
+
+// request 向指定服务器发送签名请求
+func (sm *SignServerManager) request(serverURL string, cmd string, seq uint32, data []byte) (*sign.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), sm.config.Timeout)
+	defer cancel()
+
+	body, err := json.Marshal(&sign.Request{Cmd: cmd, Seq: int(seq), Src: data})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, serverURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := sm.do(req)
+	if err != nil || len(resp.Value.Sign) == 0 {
+		// 部分签名服务器只支持 GET
+		resp, err = sm.requestGet(ctx, serverURL, cmd, seq, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sm.mu.RLock()
+	app := sm.app
+	sm.mu.RUnlock()
+	if app != nil && resp.Version != "" && resp.Version != app.CurrentVersion {
+		return nil, fmt.Errorf("%w: %s", sign.ErrVersionMismatch, resp.Version)
+	}
+	return resp, nil
+}
+
+// requestGet 使用 GET 方式请求签名
+func (sm *SignServerManager) requestGet(ctx context.Context, serverURL string, cmd string, seq uint32, data []byte) (*sign.Response, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("cmd", cmd)
+	q.Set("seq", strconv.Itoa(int(seq)))
+	q.Set("src", fmt.Sprintf("%x", data))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return sm.do(req)
+}
+
+// do 发送请求并解析签名响应
+func (sm *SignServerManager) do(req *http.Request) (*sign.Response, error) {
+	sm.mu.RLock()
+	for k, vs := range sm.headers {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	sm.mu.RUnlock()
+
+	resp, err := sm.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("签名服务器返回状态码 %d", resp.StatusCode)
+	}
+
+	var result sign.Response
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析签名响应失败: %w", err)
+	}
+	return &result, nil
+}
+
+// healthCheckLoop 定期检查所有签名服务器
+func (sm *SignServerManager) healthCheckLoop() {
+	ticker := time.NewTicker(sm.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sm.stopChan:
+			return
+		case <-ticker.C:
+			sm.CheckHealth()
+		}
+	}
+}
+
+// CheckHealth 立即检查所有签名服务器的健康状态
+func (sm *SignServerManager) CheckHealth() {
+	sm.mu.RLock()
+	servers := make([]*SignServerStatus, len(sm.servers))
+	copy(servers, sm.servers)
+	sm.mu.RUnlock()
+
+	for _, server := range servers {
+		start := time.Now()
+		resp, err := sm.request(server.URL, "wtlogin.login", 1, []byte{11, 45, 14})
+		lastError := ""
+		if errors.Is(err, sign.ErrVersionMismatch) {
+			// 服务器可用，版本不一致在签名时返回给调用方
+			sm.logger.Warnf("签名服务器 %s 的协议版本与当前版本不一致: %v", server.URL, err)
+			lastError = err.Error()
+		} else {
+			if err == nil && len(resp.Value.Sign) == 0 {
+				err = errors.New("签名结果为空")
+			}
+			if err != nil {
+				sm.markFailure(server, err)
+				continue
+			}
+		}
+
+		sm.mu.Lock()
+		if !server.Healthy {
+			sm.logger.Infof("签名服务器 %s 已恢复", server.URL)
+		}
+		server.Healthy = true
+		server.Failures = 0
+		server.Latency = time.Since(start)
+		server.LastError = lastError
+		server.LastCheck = time.Now()
+		sm.mu.Unlock()
+	}
+}
+
+// GetActiveServer 获取当前使用的签名服务器
+func (sm *SignServerManager) GetActiveServer() string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.active
+}
+
+// GetStatus 获取所有签名服务器的状态
+func (sm *SignServerManager) GetStatus() []SignServerStatus {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	status := make([]SignServerStatus, 0, len(sm.servers))
+	for _, server := range sm.servers {
+		status = append(status, *server)
+	}
+	return status
+}
+
+// AddRequestHeader 实现 sign.Provider 接口
+func (sm *SignServerManager) AddRequestHeader(header map[string]string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	for k, v := range header {
+		sm.headers.Add(k, v)
+	}
+}
+
+// AddSignServer 实现 sign.Provider 接口，追加的服务器优先级最低
+func (sm *SignServerManager) AddSignServer(signServers ...string) {
+	sm.mu.RLock()
+	priority := 0
+	if n := len(sm.servers); n > 0 {
+		priority = sm.servers[n-1].Priority + 1
+	}
+	sm.mu.RUnlock()
+
+	for _, server := range signServers {
+		sm.addServer(server, priority)
+	}
+}
+
+// GetSignServer 实现 sign.Provider 接口
+func (sm *SignServerManager) GetSignServer() []string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	servers := make([]string, 0, len(sm.servers))
+	for _, server := range sm.servers {
+		servers = append(servers, server.URL)
+	}
+	return servers
+}
+
+// SetAppInfo 实现 sign.Provider 接口
+func (sm *SignServerManager) SetAppInfo(app *auth.AppInfo) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.app = app
+	sm.headers.Set("User-Agent", "qq/"+app.CurrentVersion)
+}
+
+// Release 实现 sign.Provider 接口，停止健康检查
+func (sm *SignServerManager) Release() {
+	sm.stopOnce.Do(func() {
+		close(sm.stopChan)
+	})
+}
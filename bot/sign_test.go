@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LagrangeDev/LagrangeGo/client/auth"
+	"github.com/LagrangeDev/LagrangeGo/client/sign"
+	"github.com/vintcessun/WE-Assistant/config"
+)
+
+const testSignVersion = "3.2.15-30366"
+
+// fakeSignServer 可切换为故障或返回指定版本的签名服务器，记录收到的请求数
+type fakeSignServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	failing  bool
+	version  string
+	requests int
+}
+
+func newFakeSignServer(t *testing.T) *fakeSignServer {
+	s := &fakeSignServer{version: testSignVersion}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		if s.failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"platform":"Linux","version":"` + s.version + `","value":{"sign":"0102","extra":"","token":""}}`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeSignServer) set(failing bool, version string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+	s.version = version
+}
+
+// takeRequests 获取并清零请求数
+func (s *fakeSignServer) takeRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.requests
+	s.requests = 0
+	return n
+}
+
+func newTestSignServerManager(t *testing.T, maxFailures int, servers ...config.SignServerConfig) *SignServerManager {
+	sm := NewSignServerManager(servers, &SignConfig{Timeout: time.Second, MaxFailures: maxFailures})
+	sm.SetAppInfo(&auth.AppInfo{CurrentVersion: testSignVersion})
+	t.Cleanup(sm.Release)
+	return sm
+}
+
+func signOnce(sm *SignServerManager) error {
+	_, err := sm.Sign("wtlogin.login", 1, []byte{1})
+	return err
+}
+
+func healthy(sm *SignServerManager, url string) bool {
+	for _, status := range sm.GetStatus() {
+		if status.URL == url {
+			return status.Healthy
+		}
+	}
+	return false
+}
+
+func TestSignServerPriority(t *testing.T) {
+	low, high := newFakeSignServer(t), newFakeSignServer(t)
+	sm := newTestSignServerManager(t, 1,
+		config.SignServerConfig{URL: low.URL, Priority: 2},
+		config.SignServerConfig{URL: high.URL, Priority: 1},
+	)
+
+	if got := sm.GetSignServer(); len(got) != 2 || got[0] != high.URL {
+		t.Errorf("servers = %v, want %s first", got, high.URL)
+	}
+	if err := signOnce(sm); err != nil {
+		t.Fatal(err)
+	}
+	if sm.GetActiveServer() != high.URL || low.takeRequests() != 0 {
+		t.Errorf("the server with the lower priority value should be used, active %s", sm.GetActiveServer())
+	}
+}
+
+func TestSignServerFailover(t *testing.T) {
+	primary, backup := newFakeSignServer(t), newFakeSignServer(t)
+	sm := newTestSignServerManager(t, 1,
+		config.SignServerConfig{URL: primary.URL, Priority: 1},
+		config.SignServerConfig{URL: backup.URL, Priority: 2},
+	)
+	primary.set(true, testSignVersion)
+
+	if err := signOnce(sm); err != nil {
+		t.Fatal(err)
+	}
+	if sm.GetActiveServer() != backup.URL || healthy(sm, primary.URL) {
+		t.Errorf("should fail over to %s and mark %s unhealthy, status %+v", backup.URL, primary.URL, sm.GetStatus())
+	}
+
+	// 不可用的服务器排在健康服务器之后，不再优先尝试
+	primary.takeRequests()
+	if err := signOnce(sm); err != nil {
+		t.Fatal(err)
+	}
+	if n := primary.takeRequests(); n != 0 {
+		t.Errorf("unhealthy server received %d requests", n)
+	}
+
+	// 健康检查发现服务器恢复后重新优先使用
+	primary.set(false, testSignVersion)
+	sm.CheckHealth()
+	if !healthy(sm, primary.URL) {
+		t.Fatalf("server should recover, status %+v", sm.GetStatus())
+	}
+	if err := signOnce(sm); err != nil {
+		t.Fatal(err)
+	}
+	if sm.GetActiveServer() != primary.URL {
+		t.Errorf("active = %s, want %s", sm.GetActiveServer(), primary.URL)
+	}
+}
+
+func TestSignServerMarkFailure(t *testing.T) {
+	server := newFakeSignServer(t)
+	sm := newTestSignServerManager(t, 2, config.SignServerConfig{URL: server.URL})
+	server.set(true, testSignVersion)
+
+	if err := signOnce(sm); err == nil {
+		t.Fatal("expected an error when all servers fail")
+	}
+	if !healthy(sm, server.URL) {
+		t.Error("one failure should not reach the threshold of 2")
+	}
+	signOnce(sm)
+	if healthy(sm, server.URL) {
+		t.Error("two failures should mark the server unhealthy")
+	}
+
+	// 全部不可用时仍作为最后手段尝试
+	server.set(false, testSignVersion)
+	if err := signOnce(sm); err != nil {
+		t.Fatal(err)
+	}
+	if !healthy(sm, server.URL) {
+		t.Error("a successful sign should mark the server healthy")
+	}
+}
+
+func TestSignServerVersionMismatch(t *testing.T) {
+	primary, backup := newFakeSignServer(t), newFakeSignServer(t)
+	sm := newTestSignServerManager(t, 1,
+		config.SignServerConfig{URL: primary.URL, Priority: 1},
+		config.SignServerConfig{URL: backup.URL, Priority: 2},
+	)
+	primary.set(false, "3.2.10-25765")
+
+	if err := signOnce(sm); !errors.Is(err, sign.ErrVersionMismatch) {
+		t.Errorf("expected ErrVersionMismatch, got %v", err)
+	}
+	if !healthy(sm, primary.URL) || backup.takeRequests() != 0 {
+		t.Errorf("a version mismatch should not fail over, status %+v", sm.GetStatus())
+	}
+
+	sm.CheckHealth()
+	if !healthy(sm, primary.URL) {
+		t.Error("health check should not mark a server with another version unhealthy")
+	}
+}
@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// LoginVerifier 登录验证回调，处理密码登录过程中的滑块验证码与新设备验证
+type LoginVerifier interface {
+	// OnSlider 需要滑块验证时调用，返回验证完成后得到的 ticket 与 randstr
+	OnSlider(ctx context.Context, verifyURL string) (ticket string, randStr string, err error)
+	// OnNewDevice 需要新设备验证时调用，verifyURL 需由账号持有者在手机 QQ 上扫码确认
+	OnNewDevice(ctx context.Context, verifyURL string) error
+}
+
+// TerminalLoginVerifier 基于终端交互的登录验证器
+type TerminalLoginVerifier struct {
+	input       *bufio.Reader
+	output      io.Writer
+	qrProcessor *QRCodeProcessor
+}
+
+// NewTerminalLoginVerifier 创建终端登录验证器
+func NewTerminalLoginVerifier() *TerminalLoginVerifier {
+	return &TerminalLoginVerifier{
+		input:       bufio.NewReader(os.Stdin),
+		output:      os.Stdout,
+		qrProcessor: NewQRCodeProcessor(),
+	}
+}
+
+// OnSlider 提示用户完成滑块验证并输入 ticket 与 randstr
+func (v *TerminalLoginVerifier) OnSlider(ctx context.Context, verifyURL string) (string, string, error) {
+	fmt.Fprintln(v.output, "登录需要滑块验证，请在浏览器中打开以下链接完成验证：")
+	fmt.Fprintln(v.output, verifyURL)
+
+	ticket, err := v.readLine(ctx, "请输入 ticket: ")
+	if err != nil {
+		return "", "", err
+	}
+	randStr, err := v.readLine(ctx, "请输入 randstr: ")
+	if err != nil {
+		return "", "", err
+	}
+	return ticket, randStr, nil
+}
+
+// OnNewDevice 在终端输出新设备验证二维码
+func (v *TerminalLoginVerifier) OnNewDevice(ctx context.Context, verifyURL string) error {
+	fmt.Fprintln(v.output, "登录需要新设备验证，请使用手机 QQ 扫描以下二维码：")
+	v.qrProcessor.DisplayContent(verifyURL)
+	fmt.Fprintln(v.output, verifyURL)
+	return nil
+}
+
+// readLine 读取一行输入，上下文结束时提前返回
+func (v *TerminalLoginVerifier) readLine(ctx context.Context, prompt string) (string, error) {
+	fmt.Fprint(v.output, prompt)
+
+	type result struct {
+		line string
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		line, err := v.input.ReadString('\n')
+		ch <- result{line: strings.TrimSpace(line), err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case r := <-ch:
+		if r.err != nil && r.line == "" {
+			return "", fmt.Errorf("读取输入失败: %w", r.err)
+		}
+		return r.line, nil
+	}
+}
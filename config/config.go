@@ -1,12 +1,37 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/BurntSushi/toml"
 	"github.com/sirupsen/logrus"
 )
 
 type Config struct {
-	Bot BotConfig
+	Bot        BotConfig
+	Bots       []BotConfig      `toml:"bots"`
+	Log        LogConfig        `toml:"log"`
+	Connection ConnectionConfig `toml:"connection"`
+	Login      LoginConfig      `toml:"login"`
+	Render     RenderConfig     `toml:"render"`
+	LLM        LLMConfig        `toml:"llm"`
+	Admin      AdminConfig      `toml:"admin"`
+	Chat       ChatConfig       `toml:"chat"`
+	Plugins    PluginConfig     `toml:"plugins"`
+	Dispatch   DispatchConfig   `toml:"dispatch"`
+	// Groups 按群号覆盖 [chat] 等默认配置
+	Groups map[string]ScopeConfig `toml:"groups"`
+	// Users 按QQ号覆盖默认配置与群配置
+	Users map[string]ScopeConfig `toml:"users"`
+
+	lines     map[string]int    // 键路径所在行号，用于报告配置问题
+	origins   map[string]string // 来自环境变量或命令行参数的键
+	undecoded []string          // 无法识别的键
+	warnings  ValidationErrors  // Validate 发现的警告
 }
 
 // BotConfig 代表TOML文件中的bot部分
@@ -14,22 +39,120 @@ type BotConfig struct {
 	Account    uint32 `toml:"account"`
 	Password   string `toml:"password"`
 	SignServer string `toml:"signServer"`
+	SigFile    string `toml:"sigFile"`
+	// SigKeyFile 签名文件加密密钥文件，环境变量 WEA_SIG_KEY 优先
+	SigKeyFile string `toml:"sigKeyFile"`
+	// Platform 协议平台，默认 linux
+	Platform string `toml:"platform"`
+	// AppVersion 协议版本，默认 3.2.15-30366
+	AppVersion string `toml:"appVersion"`
+	// SignServers 多个签名服务器，按 priority 从小到大依次使用
+	SignServers []SignServerConfig `toml:"signServers"`
+	// DeviceFile 设备信息文件，默认与签名文件放在同一目录
+	DeviceFile string `toml:"deviceFile"`
+	// DeviceSeed 非零时使用固定种子生成设备信息
+	DeviceSeed int `toml:"deviceSeed"`
+	// RotateDevice 为 true 时每次启动都重新生成设备信息
+	RotateDevice bool `toml:"rotateDevice"`
+	// QRCodeWebAddr 二维码登录网页监听地址，如 "127.0.0.1:8081"，只填写端口时监听 127.0.0.1，为空时不启用
+	QRCodeWebAddr string `toml:"qrcodeWebAddr"`
+	// QRCodeMaxRefresh 二维码过期后自动刷新的最大次数，0 使用 [login] 中的配置，负数表示不限制
+	QRCodeMaxRefresh int `toml:"qrcodeMaxRefresh"`
+}
+
+// SignServerConfig 签名服务器配置
+type SignServerConfig struct {
+	URL      string `toml:"url"`
+	Priority int    `toml:"priority"`
+}
+
+// GetSignServers 获取所有签名服务器，signServer 视为优先级最高的服务器
+func (b BotConfig) GetSignServers() []SignServerConfig {
+	servers := make([]SignServerConfig, 0, len(b.SignServers)+1)
+	if b.SignServer != "" {
+		servers = append(servers, SignServerConfig{URL: b.SignServer})
+	}
+	return append(servers, b.SignServers...)
+}
+
+// GetBots 获取所有账号配置
+// 配置了 [[bots]] 时使用多账号配置，否则回退到单账号的 [bot] 部分
+func (c *Config) GetBots() []BotConfig {
+	if len(c.Bots) == 0 {
+		bot := c.Bot
+		if bot.SigFile == "" {
+			bot.SigFile = "sig.bin"
+		}
+		if bot.DeviceFile == "" {
+			bot.DeviceFile = filepath.Join(filepath.Dir(bot.SigFile), "device.json")
+		}
+		return []BotConfig{bot}
+	}
+
+	bots := make([]BotConfig, 0, len(c.Bots))
+	for _, bot := range c.Bots {
+		if bot.SigFile == "" {
+			bot.SigFile = fmt.Sprintf("sig_%d.bin", bot.Account)
+		}
+		if bot.DeviceFile == "" {
+			bot.DeviceFile = filepath.Join(filepath.Dir(bot.SigFile), fmt.Sprintf("device_%d.json", bot.Account))
+		}
+		bots = append(bots, bot)
+	}
+	return bots
 }
 
 // GlobalConfig 默认全局配置
 var GlobalConfig *Config
 
+// DefaultPath 默认配置文件路径
+const DefaultPath = "application.toml"
+
+// Load 从文件加载配置，未出现的键保持默认值
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse 解析配置内容，语法或类型错误时返回带行号的错误
+func Parse(content []byte) (*Config, error) {
+	cfg := Default()
+	md, err := toml.Decode(string(content), cfg)
+	if err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("配置文件格式错误:\n%s", parseErr.ErrorWithPosition())
+		}
+		return nil, fmt.Errorf("配置文件格式错误: %w", err)
+	}
+
+	cfg.lines = keyLines(string(content))
+	for _, key := range md.Undecoded() {
+		cfg.undecoded = append(cfg.undecoded, strings.Join(key, "."))
+	}
+	return cfg, nil
+}
+
 // Init 使用 ./application.toml 初始化全局配置
 func Init() {
-	GlobalConfig = &Config{}
-	_, err := toml.DecodeFile("application.toml", GlobalConfig)
+	cfg, err := Load(DefaultPath)
+	if err == nil {
+		err = cfg.Validate()
+	}
 	if err != nil {
-		logrus.WithField("config", "GlobalConfig").WithError(err).Panicf("unable to read global config")
+		logrus.WithField("config", "GlobalConfig").Panicf("unable to read global config: %v", err)
 	}
+	GlobalConfig = cfg
 }
 
 // InitWithContent 从字节数组中读取配置内容
 func InitWithContent(configTOMLContent []byte) {
+	if GlobalConfig == nil {
+		GlobalConfig = Default()
+	}
 	_, err := toml.Decode(string(configTOMLContent), GlobalConfig)
 	if err != nil {
 		panic(err)
@@ -0,0 +1,441 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 配置覆盖优先级: 命令行参数 > 环境变量 > 配置文件 > 默认值
+//
+// 每个键都可以通过环境变量覆盖，变量名为 EnvPrefix 加上大写下划线形式的键路径，
+// 如 bot.password -> WEA_BOT_PASSWORD，bots[1].signServer -> WEA_BOTS_1_SIGN_SERVER。
+// 命令行参数与键路径同名，如 -bot.password、-log.level，数组中的元素与映射的键使用 -set bots[1].password=xxx。
+// 覆盖配置文件中没有的数组元素或映射的键时会自动添加，如 -set groups.123456.prompt=xxx、WEA_BOTS_2_ACCOUNT。
+// 列表类型的值使用逗号分隔，如 WEA_ADMIN_OWNERS=114514,1919810。
+
+// EnvPrefix 环境变量前缀
+const EnvPrefix = "WEA_"
+
+// Flags 命令行参数
+type Flags struct {
+	ConfigPath string
+	values     map[string]string
+	sets       []string
+}
+
+// NewFlags 在 fs 上注册 -config、-set 以及每个配置键对应的参数
+func NewFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{values: make(map[string]string)}
+	fs.StringVar(&f.ConfigPath, "config", DefaultPath, "配置文件路径")
+	fs.Func("set", "覆盖任意配置键，格式为 key=value，可重复使用，配置中没有的数组元素与群号会自动添加，如 -set bots[1].password=xxx、-set groups.123456.prompt=xxx", func(s string) error {
+		if !strings.Contains(s, "=") {
+			return errors.New("格式应为 key=value")
+		}
+		f.sets = append(f.sets, s)
+		return nil
+	})
+
+	walkConfig(reflect.ValueOf(Default()).Elem(), "", func(path string, v reflect.Value) {
+		usage := fmt.Sprintf("覆盖 %s (环境变量 %s)", path, EnvName(path))
+		if v.Kind() == reflect.Bool {
+			fs.BoolFunc(path, usage, func(s string) error {
+				f.values[path] = s
+				return nil
+			})
+			return
+		}
+		fs.Func(path, usage, func(s string) error {
+			f.values[path] = s
+			return nil
+		})
+	})
+	return f
+}
+
+// LoadWithOverrides 加载配置文件并依次应用环境变量与命令行参数
+// flags 为空时使用默认配置文件；未通过 -config 指定且默认配置文件不存在时，仅使用默认值与覆盖项
+func LoadWithOverrides(flags *Flags) (*Config, error) {
+	path := DefaultPath
+	if flags != nil && flags.ConfigPath != "" {
+		path = flags.ConfigPath
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) || path != DefaultPath {
+			return nil, err
+		}
+		cfg = Default()
+	}
+
+	if err := cfg.ApplyEnv(); err != nil {
+		return nil, err
+	}
+	if flags != nil {
+		if err := flags.Apply(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// ApplyEnv 使用环境变量覆盖配置
+// 变量指向配置中还没有的数组元素或映射的键时先分配，如 WEA_BOTS_2_PASSWORD、WEA_GROUPS_123456_PROMPT
+func (c *Config) ApplyEnv() error {
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		tokens := strings.Split(strings.TrimPrefix(name, EnvPrefix), "_")
+		if path, ok := envPath(reflect.TypeOf(*c), tokens, ""); ok {
+			allocate(reflect.ValueOf(c).Elem(), strings.Split(path, "."))
+		}
+	}
+
+	var errs []error
+	walkConfig(reflect.ValueOf(c).Elem(), "", func(path string, v reflect.Value) {
+		name := EnvName(path)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return
+		}
+		if err := setValue(v, value); err != nil {
+			errs = append(errs, fmt.Errorf("环境变量 %s: %w", name, err))
+			return
+		}
+		c.setOrigin(path, "环境变量 "+name)
+	})
+	return errors.Join(errs...)
+}
+
+// Apply 使用命令行参数覆盖配置
+func (f *Flags) Apply(c *Config) error {
+	var errs []error
+
+	paths := make([]string, 0, len(f.values))
+	for path := range f.values {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := c.Set(path, f.values[path]); err != nil {
+			errs = append(errs, fmt.Errorf("参数 -%s: %w", path, err))
+			continue
+		}
+		c.setOrigin(path, "命令行参数 -"+path)
+	}
+
+	for _, set := range f.sets {
+		path, value, _ := strings.Cut(set, "=")
+		path = strings.TrimSpace(path)
+		if err := c.Set(path, value); err != nil {
+			errs = append(errs, fmt.Errorf("参数 -set %s: %w", path, err))
+			continue
+		}
+		c.setOrigin(path, "命令行参数 -set")
+	}
+	return errors.Join(errs...)
+}
+
+// Set 按键路径设置配置值，如 Set("bots[1].password", "xxx")
+// 路径中还没有的数组元素与映射的键会先分配，如只有一个账号时的 bots[1]、未配置的 groups.123456
+func (c *Config) Set(path string, value string) error {
+	allocate(reflect.ValueOf(c).Elem(), strings.Split(path, "."))
+
+	found := false
+	var err error
+	walkConfig(reflect.ValueOf(c).Elem(), "", func(p string, v reflect.Value) {
+		if p == path {
+			found = true
+			err = setValue(v, value)
+		}
+	})
+	if !found {
+		return fmt.Errorf("未知的配置项 %s", path)
+	}
+	return err
+}
+
+// setOrigin 记录配置值来自配置文件之外的来源
+func (c *Config) setOrigin(path string, origin string) {
+	if c.origins == nil {
+		c.origins = make(map[string]string)
+	}
+	c.origins[path] = origin
+}
+
+// EnvName 获取键路径对应的环境变量名
+func EnvName(path string) string {
+	var sb strings.Builder
+	sb.WriteString(EnvPrefix)
+
+	runes := []rune(path)
+	for i, r := range runes {
+		switch {
+		case r == '.' || r == '[':
+			sb.WriteByte('_')
+		case r == ']':
+		case unicode.IsUpper(r):
+			// 驼峰处的大写字母前加下划线，连续的大写缩写（如 URL）视为一个单词
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return sb.String()
+}
+
+// walkConfig 遍历配置中的每个可设置的键
+func walkConfig(v reflect.Value, prefix string, fn func(path string, v reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := tomlName(field)
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		fv := v.Field(i)
+		switch {
+		case fv.Kind() == reflect.Struct:
+			walkConfig(fv, path, fn)
+		case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Struct:
+			for j := 0; j < fv.Len(); j++ {
+				walkConfig(fv.Index(j), fmt.Sprintf("%s[%d]", path, j), fn)
+			}
+		case fv.Kind() == reflect.Map && fv.Type().Key().Kind() == reflect.String:
+			walkMap(fv, path, fn)
+		default:
+			fn(path, fv)
+		}
+	}
+}
+
+// walkMap 遍历映射中已有的键，如 groups.123456.prompt、chat.features.llm，不存在的键需先通过 allocate 分配
+// 映射中的值不可寻址，先复制再遍历，值被修改时写回映射
+func walkMap(m reflect.Value, path string, fn func(path string, v reflect.Value)) {
+	keys := m.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+
+	for _, key := range keys {
+		old := m.MapIndex(key)
+		elem := reflect.New(old.Type()).Elem()
+		elem.Set(old)
+
+		p := path + "." + key.String()
+		if elem.Kind() == reflect.Struct {
+			walkConfig(elem, p, fn)
+		} else {
+			fn(p, elem)
+		}
+
+		if !reflect.DeepEqual(elem.Interface(), old.Interface()) {
+			m.SetMapIndex(key, elem)
+		}
+	}
+}
+
+// allocate 为键路径中不存在的数组元素与映射的键分配零值，使 walkConfig 能够访问到该键
+// 下标超过数组长度时补齐中间的元素，未填写的元素由 Validate 报告
+func allocate(v reflect.Value, segments []string) {
+	if len(segments) == 0 {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		name, index := splitIndex(segments[0])
+		field, ok := fieldByName(v, name)
+		if !ok {
+			return
+		}
+		if index < 0 {
+			allocate(field, segments[1:])
+			return
+		}
+		if field.Kind() != reflect.Slice || field.Type().Elem().Kind() != reflect.Struct {
+			return
+		}
+		if n := index + 1 - field.Len(); n > 0 {
+			field.Set(reflect.AppendSlice(field, reflect.MakeSlice(field.Type(), n, n)))
+		}
+		allocate(field.Index(index), segments[1:])
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		// 映射中的值不可寻址，复制后分配再写回
+		key := reflect.ValueOf(segments[0]).Convert(v.Type().Key())
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if old := v.MapIndex(key); old.IsValid() {
+			elem.Set(old)
+		}
+		allocate(elem, segments[1:])
+		v.SetMapIndex(key, elem)
+	}
+}
+
+// splitIndex 拆分带下标的键，如 bots[1] -> (bots, 1)，没有下标时返回 -1
+func splitIndex(segment string) (string, int) {
+	name, rest, ok := strings.Cut(segment, "[")
+	if !ok || !strings.HasSuffix(rest, "]") {
+		return segment, -1
+	}
+	index, err := strconv.Atoi(strings.TrimSuffix(rest, "]"))
+	if err != nil || index < 0 {
+		return segment, -1
+	}
+	return name, index
+}
+
+// fieldByName 按 TOML 键名查找结构体字段
+func fieldByName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() && tomlName(t.Field(i)) == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// envPath 将去掉前缀并按下划线拆分的环境变量名还原为键路径
+// 数组下标为数字，映射的键为单个单词并转换为小写，如 GROUPS_123456_FEATURES_LLM -> groups.123456.features.llm
+func envPath(t reflect.Type, tokens []string, prefix string) (string, bool) {
+	if len(tokens) == 0 {
+		return prefix, prefix != ""
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := tomlName(field)
+			words := strings.Split(strings.TrimPrefix(EnvName(name), EnvPrefix), "_")
+			if len(tokens) < len(words) || !strings.EqualFold(strings.Join(tokens[:len(words)], "_"), strings.Join(words, "_")) {
+				continue
+			}
+			path := name
+			if prefix != "" {
+				path = prefix + "." + name
+			}
+			if path, ok := envPath(field.Type, tokens[len(words):], path); ok {
+				return path, true
+			}
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.Struct {
+			break
+		}
+		if index, err := strconv.Atoi(tokens[0]); err == nil && index >= 0 {
+			return envPath(t.Elem(), tokens[1:], fmt.Sprintf("%s[%d]", prefix, index))
+		}
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String {
+			return envPath(t.Elem(), tokens[1:], prefix+"."+strings.ToLower(tokens[0]))
+		}
+	}
+	return "", false
+}
+
+// tomlName 获取字段在 TOML 中的键名
+func tomlName(field reflect.StructField) string {
+	if tag, _, _ := strings.Cut(field.Tag.Get("toml"), ","); tag != "" && tag != "-" {
+		return tag
+	}
+	runes := []rune(field.Name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// setValue 将字符串解析后写入配置字段
+func setValue(v reflect.Value, s string) error {
+	s = strings.TrimSpace(s)
+
+	if v.Kind() == reflect.Ptr {
+		elem := reflect.New(v.Type().Elem())
+		if err := setValue(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%q 不是有效的时长", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q 不是有效的布尔值", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q 不是有效的整数", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q 不是有效的非负整数", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q 不是有效的数字", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		items := make([]string, 0)
+		if s != "" {
+			items = strings.Split(s, ",")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	default:
+		return fmt.Errorf("不支持覆盖 %s 类型的配置", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEnvName(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"bot.password", "WEA_BOT_PASSWORD"},
+		{"bots[1].signServer", "WEA_BOTS_1_SIGN_SERVER"},
+		{"llm.baseURL", "WEA_LLM_BASE_URL"},
+		{"groups.123456.rateLimit", "WEA_GROUPS_123456_RATE_LIMIT"},
+	}
+	for _, tt := range tests {
+		if got := EnvName(tt.path); got != tt.want {
+			t.Errorf("EnvName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSetExistingKeys(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Set("log.level", "debug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Set("connection.reconnectInterval", "30s"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Set("admin.owners", "1,2"); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Log.Level != "debug" || cfg.Connection.ReconnectInterval != 30*time.Second {
+		t.Errorf("values not set: %+v %+v", cfg.Log, cfg.Connection)
+	}
+	if !reflect.DeepEqual(cfg.Admin.Owners, []uint32{1, 2}) {
+		t.Errorf("owners = %v", cfg.Admin.Owners)
+	}
+	if err := cfg.Set("log.unknown", "x"); err == nil {
+		t.Error("unknown key should fail")
+	}
+	if err := cfg.Set("log.maxAge", "many"); err == nil {
+		t.Error("invalid integer should fail")
+	}
+}
+
+func TestSetAllocatesSliceElements(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Set("bots[1].account", "10001"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Set("bots[1].signServers[0].url", "http://sign.example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Bots) != 2 || cfg.Bots[1].Account != 10001 {
+		t.Fatalf("bots = %+v", cfg.Bots)
+	}
+	if len(cfg.Bots[1].SignServers) != 1 || cfg.Bots[1].SignServers[0].URL != "http://sign.example.com" {
+		t.Errorf("signServers = %+v", cfg.Bots[1].SignServers)
+	}
+}
+
+func TestSetAllocatesMapKeys(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Set("groups.123456.prompt", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Set("groups.123456.features.llm", "false"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Set("chat.features.draw", "false"); err != nil {
+		t.Fatal(err)
+	}
+
+	group, ok := cfg.Groups["123456"]
+	if !ok || group.Prompt == nil || *group.Prompt != "hello" {
+		t.Fatalf("groups = %+v", cfg.Groups)
+	}
+	if enabled, ok := group.Features["llm"]; !ok || enabled {
+		t.Errorf("group features = %v", group.Features)
+	}
+	if enabled, ok := cfg.Chat.Features["draw"]; !ok || enabled {
+		t.Errorf("chat features = %v", cfg.Chat.Features)
+	}
+}
+
+func TestApplyEnvAllocates(t *testing.T) {
+	t.Setenv("WEA_BOTS_0_ACCOUNT", "10001")
+	t.Setenv("WEA_BOTS_0_SIGN_SERVERS_1_URL", "http://sign.example.com")
+	t.Setenv("WEA_GROUPS_123456_RATE_LIMIT", "3")
+	t.Setenv("WEA_USERS_10001_FEATURES_LLM", "false")
+	t.Setenv("WEA_NOT_A_KEY", "x")
+
+	cfg := Default()
+	if err := cfg.ApplyEnv(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cfg.Bots) != 1 || cfg.Bots[0].Account != 10001 {
+		t.Fatalf("bots = %+v", cfg.Bots)
+	}
+	if servers := cfg.Bots[0].SignServers; len(servers) != 2 || servers[1].URL != "http://sign.example.com" {
+		t.Errorf("signServers = %+v", servers)
+	}
+	if group := cfg.Groups["123456"]; group.RateLimit == nil || *group.RateLimit != 3 {
+		t.Errorf("groups = %+v", cfg.Groups)
+	}
+	if enabled, ok := cfg.Users["10001"].Features["llm"]; !ok || enabled {
+		t.Errorf("users = %+v", cfg.Users)
+	}
+	if cfg.origins["groups.123456.rateLimit"] != "环境变量 WEA_GROUPS_123456_RATE_LIMIT" {
+		t.Errorf("origin not recorded: %v", cfg.origins)
+	}
+}
+
+func TestEnvPath(t *testing.T) {
+	tests := []struct {
+		tokens []string
+		want   string
+		ok     bool
+	}{
+		{[]string{"BOT", "SIGN", "SERVER"}, "bot.signServer", true},
+		{[]string{"BOTS", "2", "SIGN", "SERVERS", "0", "URL"}, "bots[2].signServers[0].url", true},
+		{[]string{"GROUPS", "123", "COMMAND", "PREFIX"}, "groups.123.commandPrefix", true},
+		{[]string{"LLM", "BASE", "URL"}, "llm.baseURL", true},
+		{[]string{"BOTS", "X", "ACCOUNT"}, "", false},
+		{[]string{"LOG", "LEVEL", "EXTRA"}, "", false},
+		{[]string{"SIG", "KEY"}, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := envPath(reflect.TypeOf(Config{}), tt.tokens, "")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("envPath(%v) = %q, %v, want %q, %v", tt.tokens, got, ok, tt.want, tt.ok)
+		}
+	}
+}
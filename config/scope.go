@@ -0,0 +1,99 @@
+package config
+
+import (
+	"strconv"
+	"time"
+)
+
+// 分层配置: 默认配置 -> [groups."群号"] -> [users."QQ号"]，越具体的配置优先
+//
+// 默认值来自 [chat]、[admin] 的限流与 [llm] 的群聊提示词，
+// 群或用户配置中出现的键覆盖默认值，未出现的键保持默认值。
+
+// ChatConfig 代表TOML文件中的chat部分，是所有群与私聊的默认行为
+type ChatConfig struct {
+	// CommandPrefix 命令前缀，为空时不需要前缀
+	CommandPrefix string `toml:"commandPrefix"`
+	// Features 功能开关，未列出的功能默认启用
+	Features map[string]bool `toml:"features"`
+	// CancelWords 多轮会话中结束等待的关键词
+	CancelWords []string `toml:"cancelWords"`
+	// PromptTimeout 多轮会话等待回复的默认超时时间
+	PromptTimeout time.Duration `toml:"promptTimeout"`
+}
+
+// ScopeConfig 代表TOML文件中的 groups."群号" 与 users."QQ号" 部分，只覆盖出现的键
+type ScopeConfig struct {
+	CommandPrefix   *string         `toml:"commandPrefix"`
+	Features        map[string]bool `toml:"features"`
+	RateLimit       *int            `toml:"rateLimit"`
+	RateLimitWindow *time.Duration  `toml:"rateLimitWindow"`
+	// Prompt 大模型系统提示词
+	Prompt *string `toml:"prompt"`
+}
+
+// Scope 某个群或用户最终生效的配置
+type Scope struct {
+	GroupUin        uint32 // 私聊时为 0
+	UserUin         uint32
+	CommandPrefix   string
+	Features        map[string]bool
+	RateLimit       int
+	RateLimitWindow time.Duration
+	Prompt          string // 为空时使用内置提示词
+	CancelWords     []string
+	PromptTimeout   time.Duration
+}
+
+// Enabled 检查功能是否启用，未配置的功能默认启用
+func (s *Scope) Enabled(feature string) bool {
+	enabled, ok := s.Features[feature]
+	return !ok || enabled
+}
+
+// Resolve 获取群与用户最终生效的配置，groupUin 为 0 表示私聊
+func (c *Config) Resolve(groupUin uint32, userUin uint32) *Scope {
+	scope := &Scope{
+		GroupUin:        groupUin,
+		UserUin:         userUin,
+		CommandPrefix:   c.Chat.CommandPrefix,
+		Features:        make(map[string]bool, len(c.Chat.Features)),
+		RateLimit:       c.Admin.RateLimit,
+		RateLimitWindow: c.Admin.RateLimitWindow,
+		CancelWords:     c.Chat.CancelWords,
+		PromptTimeout:   c.Chat.PromptTimeout,
+	}
+	for name, enabled := range c.Chat.Features {
+		scope.Features[name] = enabled
+	}
+
+	if groupUin != 0 {
+		scope.Prompt = c.LLM.GroupPrompt
+		if group, ok := c.Groups[strconv.FormatUint(uint64(groupUin), 10)]; ok {
+			scope.apply(group)
+		}
+	}
+	if user, ok := c.Users[strconv.FormatUint(uint64(userUin), 10)]; ok {
+		scope.apply(user)
+	}
+	return scope
+}
+
+// apply 使用群或用户配置中出现的键覆盖当前值
+func (s *Scope) apply(sc ScopeConfig) {
+	if sc.CommandPrefix != nil {
+		s.CommandPrefix = *sc.CommandPrefix
+	}
+	for name, enabled := range sc.Features {
+		s.Features[name] = enabled
+	}
+	if sc.RateLimit != nil {
+		s.RateLimit = *sc.RateLimit
+	}
+	if sc.RateLimitWindow != nil {
+		s.RateLimitWindow = *sc.RateLimitWindow
+	}
+	if sc.Prompt != nil {
+		s.Prompt = *sc.Prompt
+	}
+}
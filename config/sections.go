@@ -0,0 +1,159 @@
+package config
+
+import "time"
+
+// LogConfig 代表TOML文件中的log部分
+type LogConfig struct {
+	// Level 日志级别: trace, debug, info, warn, error, fatal, panic
+	Level       string `toml:"level"`
+	EnableFile  bool   `toml:"enableFile"`
+	EnableColor bool   `toml:"enableColor"`
+	Dir         string `toml:"dir"`
+	File        string `toml:"file"`
+	MaxSize     int64  `toml:"maxSize"` // MB
+	MaxBackups  int    `toml:"maxBackups"`
+	MaxAge      int    `toml:"maxAge"` // days
+	// Format 日志格式: text, json
+	Format string `toml:"format"`
+}
+
+// ConnectionConfig 代表TOML文件中的connection部分
+type ConnectionConfig struct {
+	AutoReconnect        bool          `toml:"autoReconnect"`
+	ReconnectInterval    time.Duration `toml:"reconnectInterval"`
+	MaxReconnectInterval time.Duration `toml:"maxReconnectInterval"`
+	ReconnectJitter      float64       `toml:"reconnectJitter"`
+	// MaxReconnectTries 最大重连次数，<=0 表示不限制
+	MaxReconnectTries    int           `toml:"maxReconnectTries"`
+	HeartbeatInterval    time.Duration `toml:"heartbeatInterval"`
+	HeartbeatTimeout     time.Duration `toml:"heartbeatTimeout"`
+	MaxHeartbeatFailures int           `toml:"maxHeartbeatFailures"`
+}
+
+// LoginConfig 代表TOML文件中的login部分
+type LoginConfig struct {
+	MaxRetries int           `toml:"maxRetries"`
+	RetryDelay time.Duration `toml:"retryDelay"`
+	// Timeout 单次登录的总超时时间，限制了二维码刷新次数时至少为 (qrcodeMaxRefresh+1) × 2 分钟
+	Timeout time.Duration `toml:"timeout"`
+	// QRCodeMaxRefresh 二维码过期后自动刷新的最大次数，<=0 表示不限制
+	QRCodeMaxRefresh int `toml:"qrcodeMaxRefresh"`
+	// QRCodeLevel 终端二维码纠错等级: L, M, Q, H
+	QRCodeLevel string `toml:"qrcodeLevel"`
+	// QRCodeQuietZone 终端二维码边框宽度
+	QRCodeQuietZone int `toml:"qrcodeQuietZone"`
+}
+
+// RenderConfig 代表TOML文件中的render部分，对应 FakeQQ-UI 渲染服务器
+type RenderConfig struct {
+	Dir string `toml:"dir"`
+	// Port 起始端口，被占用时依次尝试之后的端口
+	Port         int           `toml:"port"`
+	MaxPort      int           `toml:"maxPort"`
+	StartTimeout time.Duration `toml:"startTimeout"`
+	LogFile      string        `toml:"logFile"`
+}
+
+// LLMConfig 代表TOML文件中的llm部分
+type LLMConfig struct {
+	BaseURL string        `toml:"baseURL"`
+	APIKey  string        `toml:"apiKey"`
+	Model   string        `toml:"model"`
+	Timeout time.Duration `toml:"timeout"`
+	// GroupPrompt 群聊系统提示词，为空时使用内置提示词
+	GroupPrompt string `toml:"groupPrompt"`
+}
+
+// AdminConfig 代表TOML文件中的admin部分
+type AdminConfig struct {
+	// Owners 机器人主人，拥有全部权限
+	Owners []uint32 `toml:"owners"`
+	// Admins 管理员
+	Admins []uint32 `toml:"admins"`
+	// AllowedUsers 允许使用机器人的用户，为空时不限制
+	AllowedUsers []uint32 `toml:"allowedUsers"`
+	// RateLimit 每个用户在 RateLimitWindow 内允许的最大请求数，<=0 表示不限流
+	RateLimit       int           `toml:"rateLimit"`
+	RateLimitWindow time.Duration `toml:"rateLimitWindow"`
+}
+
+// PluginConfig 代表TOML文件中的plugins部分
+type PluginConfig struct {
+	// Disabled 不加载的插件，未列出的插件默认加载
+	Disabled []string `toml:"disabled"`
+	// StateFile 保存每个群启用或禁用的插件，为空时不保存
+	StateFile string `toml:"stateFile"`
+}
+
+// DispatchConfig 代表TOML文件中的dispatch部分，修改后需要重启
+type DispatchConfig struct {
+	// Workers 并行处理消息的工作协程数量，同一会话的消息总是依次处理
+	Workers int `toml:"workers"`
+	// QueueSize 等待处理的消息数量上限
+	QueueSize int `toml:"queueSize"`
+	// Policy 队列已满时的处理策略: block 阻塞等待, drop 丢弃新消息
+	Policy string `toml:"policy"`
+}
+
+// Default 获取默认配置，TOML文件中缺省的部分与键保持默认值
+func Default() *Config {
+	return &Config{
+		Log: LogConfig{
+			Level:       "info",
+			EnableFile:  true,
+			EnableColor: true,
+			Dir:         "logs",
+			File:        "bot.log",
+			MaxSize:     10,
+			MaxBackups:  5,
+			MaxAge:      30,
+			Format:      "text",
+		},
+		Connection: ConnectionConfig{
+			AutoReconnect:        true,
+			ReconnectInterval:    10 * time.Second,
+			MaxReconnectInterval: 5 * time.Minute,
+			ReconnectJitter:      0.2,
+			MaxReconnectTries:    5,
+			HeartbeatInterval:    30 * time.Second,
+			HeartbeatTimeout:     10 * time.Second,
+			MaxHeartbeatFailures: 3,
+		},
+		Login: LoginConfig{
+			MaxRetries:       3,
+			RetryDelay:       3 * time.Second,
+			Timeout:          5 * time.Minute,
+			QRCodeMaxRefresh: 5,
+			QRCodeLevel:      "M",
+			QRCodeQuietZone:  1,
+		},
+		Render: RenderConfig{
+			Dir:          "FakeQQ-UI",
+			Port:         3001,
+			MaxPort:      3010,
+			StartTimeout: 30 * time.Second,
+			LogFile:      "fakeqq_server.log",
+		},
+		LLM: LLMConfig{
+			BaseURL: "https://api.deepseek.com/v1",
+			Model:   "deepseek-chat",
+			Timeout: 60 * time.Second,
+		},
+		Admin: AdminConfig{
+			RateLimitWindow: time.Minute,
+		},
+		Chat: ChatConfig{
+			CommandPrefix: "/",
+			CancelWords:   []string{"取消", "退出"},
+			PromptTimeout: 2 * time.Minute,
+		},
+		Plugins: PluginConfig{
+			StateFile: "plugin_state.json",
+		},
+		Dispatch: DispatchConfig{
+			Workers:   8,
+			QueueSize: 1000,
+			Policy:    "block",
+		},
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package config
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyReload 收到 SIGHUP 时通知重新加载配置
+func notifyReload(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGHUP)
+}
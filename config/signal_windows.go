@@ -0,0 +1,8 @@
+//go:build windows
+
+package config
+
+import "os"
+
+// notifyReload Windows 下没有 SIGHUP，仅依靠文件监视重新加载配置
+func notifyReload(ch chan<- os.Signal) {}
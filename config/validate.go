@@ -0,0 +1,489 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LagrangeDev/LagrangeGo/client/auth"
+)
+
+// ValidationError 单个配置问题
+type ValidationError struct {
+	Key     string // TOML 键路径，如 bots[1].signServer
+	Line    int    // 所在行号，键未出现在配置文件中时为 0
+	Source  string // 值来自环境变量或命令行参数时记录来源
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	if e.Source != "" {
+		return fmt.Sprintf("%s (%s): %s", e.Key, e.Source, e.Message)
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("%s (第 %d 行): %s", e.Key, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+// ValidationErrors 配置中发现的所有问题
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "配置文件中有 %d 个问题:", len(errs))
+	for _, err := range errs {
+		sb.WriteString("\n  - ")
+		sb.WriteString(err.Error())
+	}
+	return sb.String()
+}
+
+// validator 收集配置问题
+type validator struct {
+	lines    map[string]int
+	origins  map[string]string
+	errs     ValidationErrors
+	warnings ValidationErrors
+}
+
+// addf 记录一个问题，键未出现在配置文件中时使用所在部分的行号
+func (v *validator) addf(key string, format string, args ...interface{}) {
+	v.errs = append(v.errs, v.newError(key, format, args...))
+}
+
+// warnf 记录一个不影响启动的警告
+func (v *validator) warnf(key string, format string, args ...interface{}) {
+	v.warnings = append(v.warnings, v.newError(key, format, args...))
+}
+
+// newError 创建配置问题，记录键的来源或所在行号
+func (v *validator) newError(key string, format string, args ...interface{}) ValidationError {
+	if source, ok := v.origins[key]; ok {
+		return ValidationError{Key: key, Source: source, Message: fmt.Sprintf(format, args...)}
+	}
+
+	line := 0
+	for k := key; k != ""; k = parentKey(k) {
+		if l, ok := v.lines[k]; ok {
+			line = l
+			break
+		}
+	}
+	return ValidationError{Key: key, Line: line, Message: fmt.Sprintf(format, args...)}
+}
+
+// parentKey 获取键路径的上一级
+func parentKey(key string) string {
+	if i := strings.LastIndexAny(key, ".["); i > 0 {
+		return key[:i]
+	}
+	return ""
+}
+
+// Validate 检查配置是否有效，一次性返回发现的所有问题
+// 返回的错误类型为 ValidationErrors，问题按行号排序，不在配置文件中的键排在最后；
+// 不影响启动的问题通过 Warnings 获取
+func (c *Config) Validate() error {
+	v := &validator{lines: c.lines, origins: c.origins}
+
+	for _, key := range c.undecoded {
+		v.addf(key, "未知的配置项")
+	}
+
+	c.validateBots(v)
+	c.validateLog(v)
+	c.validateConnection(v)
+	c.validateLogin(v)
+	c.validateRender(v)
+	c.validateLLM(v)
+	c.validateAdmin(v)
+	c.validateChat(v)
+	c.validateDispatch(v)
+	c.validateScopes(v, "groups", "群号", c.Groups)
+	c.validateScopes(v, "users", "QQ号", c.Users)
+
+	sortByLine(v.warnings)
+	c.warnings = v.warnings
+	if len(v.errs) == 0 {
+		return nil
+	}
+	sortByLine(v.errs)
+	return v.errs
+}
+
+// Warnings 获取最近一次 Validate 发现的警告
+func (c *Config) Warnings() ValidationErrors {
+	return c.warnings
+}
+
+// sortByLine 按行号排序，不在配置文件中的键排在最后
+func sortByLine(errs ValidationErrors) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		li, lj := errs[i].Line, errs[j].Line
+		if li == 0 || lj == 0 {
+			return lj == 0 && li != 0
+		}
+		return li < lj
+	})
+}
+
+// validateBots 检查账号配置
+func (c *Config) validateBots(v *validator) {
+	bots := c.GetBots()
+	prefix := func(i int) string {
+		if len(c.Bots) == 0 {
+			return "bot"
+		}
+		return fmt.Sprintf("bots[%d]", i)
+	}
+
+	seen := make(map[uint32]int)
+	for i, bot := range bots {
+		p := prefix(i)
+		if bot.Account == 0 {
+			v.addf(p+".account", "必须填写账号")
+		} else if first, ok := seen[bot.Account]; ok {
+			v.addf(p+".account", "账号 %d 与 %s 重复", bot.Account, prefix(first))
+		} else {
+			seen[bot.Account] = i
+		}
+
+		if bot.SignServer != "" {
+			checkURL(v, p+".signServer", bot.SignServer)
+		}
+		for j, server := range bot.SignServers {
+			key := fmt.Sprintf("%s.signServers[%d]", p, j)
+			if server.URL == "" {
+				v.addf(key+".url", "必须填写签名服务器地址")
+			} else {
+				checkURL(v, key+".url", server.URL)
+			}
+		}
+
+		platform := bot.Platform
+		if platform == "" {
+			platform = "linux"
+		}
+		versions, ok := auth.AppList[platform]
+		if !ok {
+			v.addf(p+".platform", "不支持的协议平台 %q", bot.Platform)
+		} else if bot.AppVersion != "" {
+			if _, ok := versions[bot.AppVersion]; !ok {
+				v.addf(p+".appVersion", "平台 %s 不支持协议版本 %q", platform, bot.AppVersion)
+			}
+		}
+
+		if bot.DeviceSeed != 0 && bot.RotateDevice {
+			v.addf(p+".rotateDevice", "不能与 deviceSeed 同时使用")
+		}
+		if bot.QRCodeWebAddr != "" {
+			if host, _, err := net.SplitHostPort(bot.QRCodeWebAddr); err != nil {
+				v.addf(p+".qrcodeWebAddr", "监听地址格式错误，应为 host:port 或 :port")
+			} else if !isLoopback(host) {
+				v.warnf(p+".qrcodeWebAddr", "监听 %s 时其他机器也可以访问二维码，扫码者即可登录该账号", host)
+			}
+		}
+	}
+}
+
+// isLoopback 检查监听的主机是否只允许本机访问，为空时视为 127.0.0.1
+func isLoopback(host string) bool {
+	if host == "" || host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// validateLog 检查日志配置
+func (c *Config) validateLog(v *validator) {
+	switch strings.ToLower(c.Log.Level) {
+	case "trace", "debug", "info", "warn", "warning", "error", "fatal", "panic":
+	default:
+		v.addf("log.level", "未知的日志级别 %q，可选 trace, debug, info, warn, error, fatal, panic", c.Log.Level)
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		v.addf("log.format", "未知的日志格式 %q，可选 text, json", c.Log.Format)
+	}
+	if c.Log.EnableFile && c.Log.File == "" {
+		v.addf("log.file", "启用文件日志时必须填写日志文件名")
+	}
+	checkNonNegative(v, "log.maxSize", int(c.Log.MaxSize))
+	checkNonNegative(v, "log.maxBackups", c.Log.MaxBackups)
+	checkNonNegative(v, "log.maxAge", c.Log.MaxAge)
+}
+
+// validateConnection 检查连接配置
+func (c *Config) validateConnection(v *validator) {
+	conn := c.Connection
+	if conn.AutoReconnect {
+		checkPositiveDuration(v, "connection.reconnectInterval", conn.ReconnectInterval)
+		if conn.MaxReconnectInterval > 0 && conn.MaxReconnectInterval < conn.ReconnectInterval {
+			v.addf("connection.maxReconnectInterval", "不能小于 reconnectInterval (%v)", conn.ReconnectInterval)
+		}
+	}
+	if conn.ReconnectJitter < 0 || conn.ReconnectJitter > 1 {
+		v.addf("connection.reconnectJitter", "必须在 0 到 1 之间")
+	}
+	checkNonNegativeDuration(v, "connection.heartbeatInterval", conn.HeartbeatInterval)
+	if conn.HeartbeatInterval > 0 {
+		checkPositiveDuration(v, "connection.heartbeatTimeout", conn.HeartbeatTimeout)
+	}
+	checkNonNegative(v, "connection.maxHeartbeatFailures", conn.MaxHeartbeatFailures)
+}
+
+// validateLogin 检查登录配置
+func (c *Config) validateLogin(v *validator) {
+	if c.Login.MaxRetries < 1 {
+		v.addf("login.maxRetries", "至少为 1")
+	}
+	checkNonNegativeDuration(v, "login.retryDelay", c.Login.RetryDelay)
+	checkPositiveDuration(v, "login.timeout", c.Login.Timeout)
+	switch strings.ToUpper(c.Login.QRCodeLevel) {
+	case "L", "M", "Q", "H":
+	default:
+		v.addf("login.qrcodeLevel", "未知的二维码纠错等级 %q，可选 L, M, Q, H", c.Login.QRCodeLevel)
+	}
+	checkNonNegative(v, "login.qrcodeQuietZone", c.Login.QRCodeQuietZone)
+}
+
+// validateRender 检查渲染服务器配置
+func (c *Config) validateRender(v *validator) {
+	if c.Render.Dir == "" {
+		v.addf("render.dir", "必须填写 FakeQQ-UI 目录")
+	}
+	if c.Render.Port < 1 || c.Render.Port > 65535 {
+		v.addf("render.port", "端口必须在 1 到 65535 之间")
+	}
+	if c.Render.MaxPort < c.Render.Port || c.Render.MaxPort > 65535 {
+		v.addf("render.maxPort", "必须在 port (%d) 到 65535 之间", c.Render.Port)
+	}
+	checkPositiveDuration(v, "render.startTimeout", c.Render.StartTimeout)
+}
+
+// validateLLM 检查大模型接口配置
+func (c *Config) validateLLM(v *validator) {
+	checkURL(v, "llm.baseURL", c.LLM.BaseURL)
+	if c.LLM.Model == "" {
+		v.addf("llm.model", "必须填写模型名称")
+	}
+	checkNonNegativeDuration(v, "llm.timeout", c.LLM.Timeout)
+	if c.LLM.APIKey == "" {
+		v.warnf("llm.apiKey", "未配置，大模型功能不可用")
+	}
+}
+
+// validateAdmin 检查管理配置
+func (c *Config) validateAdmin(v *validator) {
+	for i, uin := range c.Admin.Owners {
+		if uin == 0 {
+			v.addf(fmt.Sprintf("admin.owners[%d]", i), "账号不能为 0")
+		}
+	}
+	for i, uin := range c.Admin.Admins {
+		if uin == 0 {
+			v.addf(fmt.Sprintf("admin.admins[%d]", i), "账号不能为 0")
+		}
+	}
+	checkNonNegative(v, "admin.rateLimit", c.Admin.RateLimit)
+	if c.Admin.RateLimit > 0 {
+		checkPositiveDuration(v, "admin.rateLimitWindow", c.Admin.RateLimitWindow)
+	}
+}
+
+// validateChat 检查聊天配置
+func (c *Config) validateChat(v *validator) {
+	checkPositiveDuration(v, "chat.promptTimeout", c.Chat.PromptTimeout)
+}
+
+// validateDispatch 检查消息分发配置
+func (c *Config) validateDispatch(v *validator) {
+	if c.Dispatch.Workers < 1 {
+		v.addf("dispatch.workers", "至少需要 1 个工作协程")
+	}
+	if c.Dispatch.QueueSize < 1 {
+		v.addf("dispatch.queueSize", "队列容量必须大于 0")
+	}
+	switch c.Dispatch.Policy {
+	case "block", "drop":
+	default:
+		v.addf("dispatch.policy", "未知的队列策略 %q，可选 block, drop", c.Dispatch.Policy)
+	}
+}
+
+// validateScopes 检查群或用户配置
+func (c *Config) validateScopes(v *validator, section string, idName string, scopes map[string]ScopeConfig) {
+	ids := make([]string, 0, len(scopes))
+	for id := range scopes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		p := section + "." + id
+		if uin, err := strconv.ParseUint(id, 10, 32); err != nil || uin == 0 {
+			v.addf(p, "%q 不是有效的%s", id, idName)
+		}
+		scope := scopes[id]
+		if scope.RateLimit != nil {
+			checkNonNegative(v, p+".rateLimit", *scope.RateLimit)
+		}
+		if scope.RateLimitWindow != nil {
+			checkPositiveDuration(v, p+".rateLimitWindow", *scope.RateLimitWindow)
+		}
+	}
+}
+
+// checkURL 检查是否为有效的 http(s) 地址
+func checkURL(v *validator, key string, value string) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf(key, "%q 不是有效的 http(s) 地址", value)
+	}
+}
+
+func checkNonNegative(v *validator, key string, value int) {
+	if value < 0 {
+		v.addf(key, "不能为负数")
+	}
+}
+
+func checkPositiveDuration(v *validator, key string, value time.Duration) {
+	if value <= 0 {
+		v.addf(key, "必须为正的时长，如 \"30s\"")
+	}
+}
+
+func checkNonNegativeDuration(v *validator, key string, value time.Duration) {
+	if value < 0 {
+		v.addf(key, "时长不能为负数")
+	}
+}
+
+// keyLines 扫描配置文件，记录每个键与表头所在的行号
+// 数组表中的键同时以带下标 (bots[1].account) 与不带下标 (bots.account，首次出现) 的形式记录
+func keyLines(content string) map[string]int {
+	lines := make(map[string]int)
+	arrayIndex := make(map[string]int)
+	table := ""
+
+	record := func(indexed, plain string, line int) {
+		if _, ok := lines[indexed]; !ok {
+			lines[indexed] = line
+		}
+		if _, ok := lines[plain]; !ok {
+			lines[plain] = line
+		}
+	}
+
+	// resolve 将表头中的数组表替换为当前下标
+	resolve := func(name string) (string, string) {
+		parts := splitKey(name)
+		indexed := make([]string, 0, len(parts))
+		for i := range parts {
+			plain := strings.Join(parts[:i+1], ".")
+			if idx, ok := arrayIndex[plain]; ok {
+				indexed = append(indexed, fmt.Sprintf("%s[%d]", parts[i], idx))
+			} else {
+				indexed = append(indexed, parts[i])
+			}
+		}
+		return strings.Join(indexed, "."), strings.Join(parts, ".")
+	}
+
+	plainTable := ""
+	for i, raw := range strings.Split(content, "\n") {
+		lineNo := i + 1
+		line := strings.TrimSpace(stripComment(raw))
+		if line == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "[[") && strings.HasSuffix(line, "]]"):
+			name := strings.Join(splitKey(strings.TrimSpace(line[2:len(line)-2])), ".")
+			if idx, ok := arrayIndex[name]; ok {
+				arrayIndex[name] = idx + 1
+			} else {
+				arrayIndex[name] = 0
+			}
+			// 新的数组元素开始时，其下的子数组重新计数
+			for k := range arrayIndex {
+				if strings.HasPrefix(k, name+".") {
+					delete(arrayIndex, k)
+				}
+			}
+			table, plainTable = resolve(name)
+			record(table, plainTable, lineNo)
+
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			table, plainTable = resolve(strings.TrimSpace(line[1 : len(line)-1]))
+			record(table, plainTable, lineNo)
+
+		default:
+			eq := strings.Index(line, "=")
+			if eq <= 0 {
+				continue
+			}
+			key := strings.Join(splitKey(strings.TrimSpace(line[:eq])), ".")
+			if table == "" {
+				record(key, key, lineNo)
+			} else {
+				record(table+"."+key, plainTable+"."+key, lineNo)
+			}
+		}
+	}
+	return lines
+}
+
+// splitKey 拆分点分隔的键，去掉引号
+func splitKey(key string) []string {
+	var parts []string
+	var sb strings.Builder
+	quote := byte(0)
+	for i := 0; i < len(key); i++ {
+		ch := key[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			} else {
+				sb.WriteByte(ch)
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '.':
+			parts = append(parts, strings.TrimSpace(sb.String()))
+			sb.Reset()
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return append(parts, strings.TrimSpace(sb.String()))
+}
+
+// stripComment 去掉行尾注释，忽略字符串中的 #
+func stripComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote == '"' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// validationErrors 解析并检查配置，返回发现的问题
+func validationErrors(t *testing.T, content string) ValidationErrors {
+	t.Helper()
+	cfg, err := Parse([]byte(content))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	err = cfg.Validate()
+	if err == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %T: %v", err, err)
+	}
+	return errs
+}
+
+func TestValidateDefault(t *testing.T) {
+	cfg := Default()
+	cfg.Bot.Account = 114514
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default config should be valid: %v", err)
+	}
+}
+
+func TestValidateCollectsAllErrors(t *testing.T) {
+	errs := validationErrors(t, `
+[bot]
+account = 114514
+signServer = "not a url"
+
+[log]
+level = "loud"
+
+[dispatch]
+workers = 0
+policy = "spill"
+`)
+
+	want := []struct {
+		key  string
+		line int
+	}{
+		{"bot.signServer", 4},
+		{"log.level", 7},
+		{"dispatch.workers", 10},
+		{"dispatch.policy", 11},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d:\n%v", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		if errs[i].Key != w.key || errs[i].Line != w.line {
+			t.Errorf("error %d: got %s (line %d), want %s (line %d)", i, errs[i].Key, errs[i].Line, w.key, w.line)
+		}
+	}
+}
+
+func TestValidateUnknownKey(t *testing.T) {
+	errs := validationErrors(t, `
+[bot]
+account = 114514
+acount = 1
+`)
+	if len(errs) != 1 || errs[0].Key != "bot.acount" || errs[0].Line != 4 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+}
+
+func TestValidateBots(t *testing.T) {
+	errs := validationErrors(t, `
+[[bots]]
+account = 114514
+
+[[bots]]
+account = 114514
+deviceSeed = 1
+rotateDevice = true
+
+[[bots.signServers]]
+priority = 1
+`)
+
+	keys := make([]string, 0, len(errs))
+	for _, err := range errs {
+		keys = append(keys, err.Key)
+	}
+	got := strings.Join(keys, ",")
+	want := "bots[1].account,bots[1].rotateDevice,bots[1].signServers[0].url"
+	if got != want {
+		t.Errorf("got errors %s, want %s", got, want)
+	}
+}
+
+func TestValidateScopes(t *testing.T) {
+	errs := validationErrors(t, `
+[bot]
+account = 114514
+
+[groups.abc]
+commandPrefix = "#"
+
+[groups.123]
+rateLimit = -1
+`)
+
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if errs[0].Key != "groups.abc" || errs[1].Key != "groups.123.rateLimit" {
+		t.Errorf("unexpected errors %v", errs)
+	}
+}
+
+func TestValidateWarnings(t *testing.T) {
+	cfg, err := Parse([]byte(`
+[bot]
+account = 114514
+qrcodeWebAddr = "0.0.0.0:8081"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("warnings should not fail validation: %v", err)
+	}
+
+	warnings := cfg.Warnings()
+	keys := make(map[string]bool)
+	for _, warning := range warnings {
+		keys[warning.Key] = true
+	}
+	if !keys["bot.qrcodeWebAddr"] || !keys["llm.apiKey"] || len(warnings) != 2 {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+}
+
+func TestValidationErrorSource(t *testing.T) {
+	cfg := Default()
+	cfg.Bot.Account = 114514
+	if err := cfg.Set("log.level", "loud"); err != nil {
+		t.Fatal(err)
+	}
+	cfg.setOrigin("log.level", "命令行参数 -log.level")
+
+	var errs ValidationErrors
+	if !errors.As(cfg.Validate(), &errs) || len(errs) != 1 {
+		t.Fatalf("expected one error, got %v", errs)
+	}
+	if errs[0].Source != "命令行参数 -log.level" || !strings.Contains(errs[0].Error(), "命令行参数") {
+		t.Errorf("error should report its source: %v", errs[0])
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"", true},
+		{"localhost", true},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"0.0.0.0", false},
+		{"192.168.1.2", false},
+		{"example.com", false},
+	}
+	for _, tt := range tests {
+		if got := isLoopback(tt.host); got != tt.want {
+			t.Errorf("isLoopback(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
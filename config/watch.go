@@ -0,0 +1,248 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vintcessun/WE-Assistant/utils"
+)
+
+// liveKeys 可以在运行时生效的配置部分，其余键修改后需要重启
+var liveKeys = []string{"admin", "llm", "chat", "groups", "users"}
+
+// RequiresRestart 检查修改该键后是否需要重启才能生效
+func RequiresRestart(key string) bool {
+	for _, prefix := range liveKeys {
+		if key == prefix || strings.HasPrefix(key, prefix+".") || strings.HasPrefix(key, prefix+"[") {
+			return false
+		}
+	}
+	return true
+}
+
+// Change 配置项的变化
+type Change struct {
+	Key             string
+	Old             interface{} // 键在旧配置中不存在时为 nil
+	New             interface{} // 键在新配置中不存在时为 nil
+	RestartRequired bool
+}
+
+// Diff 比较两份配置，返回所有发生变化的键
+func Diff(oldConfig, newConfig *Config) []Change {
+	oldValues := make(map[string]interface{})
+	walkConfig(reflect.ValueOf(oldConfig).Elem(), "", func(path string, v reflect.Value) {
+		oldValues[path] = plainValue(v)
+	})
+
+	changes := make([]Change, 0)
+	walkConfig(reflect.ValueOf(newConfig).Elem(), "", func(path string, v reflect.Value) {
+		newValue := plainValue(v)
+		oldValue := oldValues[path]
+		delete(oldValues, path)
+		if equalValue(oldValue, newValue) {
+			return
+		}
+		changes = append(changes, Change{Key: path, Old: oldValue, New: newValue, RestartRequired: RequiresRestart(path)})
+	})
+	for path, oldValue := range oldValues {
+		if oldValue == nil {
+			continue
+		}
+		changes = append(changes, Change{Key: path, Old: oldValue, RestartRequired: RequiresRestart(path)})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// plainValue 获取配置值，指针取其指向的值，未设置时为 nil
+func plainValue(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		return v.Elem().Interface()
+	}
+	return v.Interface()
+}
+
+// equalValue 比较两个配置值，空列表与未设置的列表视为相同
+func equalValue(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == reflect.Slice && vb.Kind() == reflect.Slice && va.Len() == 0 && vb.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// ReloadHandler 配置重新加载回调
+type ReloadHandler func(oldConfig, newConfig *Config, changes []Change)
+
+// Watcher 监视配置文件，文件变化或收到 SIGHUP 时重新加载
+type Watcher struct {
+	flags    *Flags
+	path     string
+	current  *Config
+	handlers []ReloadHandler
+	interval time.Duration
+	modTime  time.Time
+	size     int64
+	mu       sync.Mutex
+	stopChan chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+	logger   utils.Logger
+}
+
+// NewWatcher 创建配置监视器，flags 与 LoadWithOverrides 相同，重新加载时同样应用环境变量与命令行参数
+func NewWatcher(flags *Flags, current *Config) *Watcher {
+	path := DefaultPath
+	if flags != nil && flags.ConfigPath != "" {
+		path = flags.ConfigPath
+	}
+
+	w := &Watcher{
+		flags:    flags,
+		path:     path,
+		current:  current,
+		interval: 2 * time.Second,
+		stopChan: make(chan struct{}),
+		logger:   utils.GetLogger().WithField("module", "config"),
+	}
+	w.modTime, w.size = w.stat()
+	return w
+}
+
+// OnReload 注册配置重新加载回调
+func (w *Watcher) OnReload(handler ReloadHandler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.handlers = append(w.handlers, handler)
+}
+
+// Current 获取当前生效的配置
+func (w *Watcher) Current() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Start 开始监视配置文件与 SIGHUP
+func (w *Watcher) Start() {
+	w.wg.Add(1)
+	go w.watchLoop()
+}
+
+// Stop 停止监视
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stopChan)
+	})
+	w.wg.Wait()
+}
+
+// watchLoop 定期检查配置文件的修改时间与大小
+func (w *Watcher) watchLoop() {
+	defer w.wg.Done()
+
+	hup := make(chan os.Signal, 1)
+	notifyReload(hup)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopChan:
+			return
+		case <-hup:
+			w.logger.Info("收到 SIGHUP，重新加载配置")
+			w.reloadAndLog()
+		case <-ticker.C:
+			modTime, size := w.stat()
+			if modTime.IsZero() || (modTime.Equal(w.modTime) && size == w.size) {
+				continue
+			}
+			w.modTime, w.size = modTime, size
+			w.logger.Infof("配置文件 %s 已修改，重新加载配置", w.path)
+			w.reloadAndLog()
+		}
+	}
+}
+
+// stat 获取配置文件的修改时间与大小，文件不存在时返回零值
+func (w *Watcher) stat() (time.Time, int64) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return time.Time{}, 0
+	}
+	return info.ModTime(), info.Size()
+}
+
+// reloadAndLog 重新加载配置，失败时保留当前配置并记录错误
+func (w *Watcher) reloadAndLog() {
+	if err := w.Reload(); err != nil {
+		w.logger.Errorf("重新加载配置失败，继续使用当前配置: %v", err)
+	}
+}
+
+// Reload 立即重新加载并检查配置，检查通过后替换当前配置并通知回调
+func (w *Watcher) Reload() error {
+	newConfig, err := LoadWithOverrides(w.flags)
+	if err != nil {
+		return err
+	}
+	if err := newConfig.Validate(); err != nil {
+		return err
+	}
+	for _, warning := range newConfig.Warnings() {
+		w.logger.Warnf("配置警告: %v", warning)
+	}
+
+	w.mu.Lock()
+	oldConfig := w.current
+	changes := Diff(oldConfig, newConfig)
+	if len(changes) == 0 {
+		w.mu.Unlock()
+		w.logger.Info("配置未发生变化")
+		return nil
+	}
+	w.current = newConfig
+	GlobalConfig = newConfig
+	handlers := make([]ReloadHandler, len(w.handlers))
+	copy(handlers, w.handlers)
+	w.mu.Unlock()
+
+	var restartKeys []string
+	for _, change := range changes {
+		if change.RestartRequired {
+			restartKeys = append(restartKeys, change.Key)
+		}
+	}
+	w.logger.Infof("配置已重新加载，%d 项发生变化", len(changes))
+	if len(restartKeys) > 0 {
+		w.logger.Warnf("以下配置项需要重启后生效: %s", strings.Join(restartKeys, ", "))
+	}
+
+	for _, handler := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					w.logger.Errorf("配置重新加载回调发生panic: %v", fmt.Sprint(r))
+				}
+			}()
+			handler(oldConfig, newConfig, changes)
+		}()
+	}
+	return nil
+}
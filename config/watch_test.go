@@ -0,0 +1,174 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const watchBaseConfig = `
+[bot]
+account = 114514
+
+[admin]
+rateLimit = 5
+`
+
+func TestDiff(t *testing.T) {
+	oldConfig := Default()
+	newConfig := Default()
+	newConfig.Admin.RateLimit = 10
+	newConfig.Log.Level = "debug"
+
+	changes := Diff(oldConfig, newConfig)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %+v", changes)
+	}
+	got := make(map[string]Change)
+	for _, change := range changes {
+		got[change.Key] = change
+	}
+
+	if change, ok := got["admin.rateLimit"]; !ok || change.Old != 0 || change.New != 10 || change.RestartRequired {
+		t.Errorf("admin.rateLimit: got %+v", change)
+	}
+	if change, ok := got["log.level"]; !ok || change.Old != "info" || change.New != "debug" || !change.RestartRequired {
+		t.Errorf("log.level: got %+v", change)
+	}
+	if changes[0].Key > changes[1].Key {
+		t.Errorf("changes not sorted: %s before %s", changes[0].Key, changes[1].Key)
+	}
+}
+
+func TestDiffMapKeys(t *testing.T) {
+	oldConfig := Default()
+	newConfig := Default()
+	prefix := "#"
+	newConfig.Groups = map[string]ScopeConfig{"123": {CommandPrefix: &prefix}}
+
+	changes := Diff(oldConfig, newConfig)
+	if len(changes) != 1 || changes[0].Key != "groups.123.commandPrefix" || changes[0].Old != nil || changes[0].New != "#" {
+		t.Errorf("unexpected changes %+v", changes)
+	}
+	if changes := Diff(newConfig, oldConfig); len(changes) != 1 || changes[0].New != nil {
+		t.Errorf("unexpected changes after removing the group %+v", changes)
+	}
+}
+
+func TestDiffNoChanges(t *testing.T) {
+	oldConfig := Default()
+	newConfig := Default()
+	newConfig.Admin.Owners = []uint32{}
+
+	if changes := Diff(oldConfig, newConfig); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+}
+
+func TestRequiresRestart(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"admin.owners", false},
+		{"llm.model", false},
+		{"groups.123.features", false},
+		{"log.level", true},
+		{"bots[0].account", true},
+		{"dispatch.workers", true},
+		{"administrator", true},
+	}
+	for _, tt := range tests {
+		if got := RequiresRestart(tt.key); got != tt.want {
+			t.Errorf("RequiresRestart(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+// newTestWatcher 在临时目录中创建配置文件与监视器
+func newTestWatcher(t *testing.T, content string) (*Watcher, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "application.toml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWatcher(&Flags{ConfigPath: path}, cfg)
+	w.interval = 10 * time.Millisecond
+	return w, path
+}
+
+func TestWatcherReloadsOnFileChange(t *testing.T) {
+	w, path := newTestWatcher(t, watchBaseConfig)
+	reloaded := make(chan []Change, 1)
+	w.OnReload(func(oldConfig, newConfig *Config, changes []Change) {
+		reloaded <- changes
+	})
+	w.Start()
+	defer w.Stop()
+
+	if err := os.WriteFile(path, []byte(watchBaseConfig+"rateLimitWindow = \"2m\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case changes := <-reloaded:
+		if len(changes) != 1 || changes[0].Key != "admin.rateLimitWindow" || changes[0].New != 2*time.Minute {
+			t.Errorf("unexpected changes %+v", changes)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("config was not reloaded")
+	}
+	if got := w.Current().Admin.RateLimitWindow; got != 2*time.Minute {
+		t.Errorf("current rateLimitWindow = %v, want 2m", got)
+	}
+}
+
+func TestWatcherKeepsConfigWhenInvalid(t *testing.T) {
+	w, path := newTestWatcher(t, watchBaseConfig)
+	called := false
+	w.OnReload(func(oldConfig, newConfig *Config, changes []Change) {
+		called = true
+	})
+
+	if err := os.WriteFile(path, []byte(watchBaseConfig+"rateLimitWindow = \"-1s\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := w.Reload()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+	if called {
+		t.Error("handler called for invalid config")
+	}
+	if got := w.Current().Admin.RateLimitWindow; got != time.Minute {
+		t.Errorf("current rateLimitWindow = %v, want 1m", got)
+	}
+}
+
+func TestWatcherHandlerPanic(t *testing.T) {
+	w, path := newTestWatcher(t, watchBaseConfig)
+	called := false
+	w.OnReload(func(oldConfig, newConfig *Config, changes []Change) {
+		panic("boom")
+	})
+	w.OnReload(func(oldConfig, newConfig *Config, changes []Change) {
+		called = true
+	})
+
+	if err := os.WriteFile(path, []byte(watchBaseConfig+"rateLimitWindow = \"2m\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Error("handler after a panicking handler was not called")
+	}
+}
@@ -0,0 +1,616 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/LagrangeDev/LagrangeGo/message"
+	"github.com/vintcessun/WE-Assistant/utils"
+)
+
+// 命令定义: 声明命令的位置参数与 --名称 形式的选项，注册后自动解析参数，
+// 参数错误时向用户回复错误与用法，并用于生成 /help。
+//
+//   cmd := NewCommand("remind", handler).
+//       Describe("定时提醒").
+//       Arg("after", ArgDuration, "多久之后提醒").
+//       Arg("content", ArgRest, "提醒内容").
+//       Option("user", ArgUser, "提醒的对象，默认为自己", nil)
+
+var (
+	// ErrInvalidCommand 命令定义错误
+	ErrInvalidCommand = errors.New("命令定义错误")
+	// ErrUsage 命令参数错误
+	ErrUsage = errors.New("命令参数错误")
+)
+
+// commandArgsKey 解析后的参数在元数据中的键
+const commandArgsKey = "command_args"
+
+// ArgType 参数类型
+type ArgType int
+
+const (
+	// ArgString 文本，包含空格时使用引号
+	ArgString ArgType = iota
+	// ArgInt 整数
+	ArgInt
+	// ArgDuration 时长，如 10m、1h30m，纯数字表示秒
+	ArgDuration
+	// ArgUser 用户，@成员或QQ号
+	ArgUser
+	// ArgImage 图片
+	ArgImage
+	// ArgRest 剩余的全部内容，只能作为最后一个位置参数
+	ArgRest
+)
+
+func (t ArgType) String() string {
+	switch t {
+	case ArgInt:
+		return "整数"
+	case ArgDuration:
+		return "时长"
+	case ArgUser:
+		return "@成员或QQ号"
+	case ArgImage:
+		return "图片"
+	case ArgRest:
+		return "文本..."
+	default:
+		return "文本"
+	}
+}
+
+// CommandArg 命令参数定义
+type CommandArg struct {
+	Name string
+	Type ArgType
+	Help string
+	// Named 为 true 时是 --名称 形式的选项，否则按位置解析
+	Named bool
+	// Required 为 true 时必须提供，选项总是可选的
+	Required bool
+	// Default 未提供时的值，类型需与参数类型对应: string、int、time.Duration、uint32、*message.ImageElement
+	Default interface{}
+	// Validate 检查用户提供的值，返回的错误作为用法错误回复给用户
+	Validate func(value interface{}) error
+}
+
+// Command 命令定义
+type Command struct {
+	Name        string
+	Aliases     []string
+	Description string
+	Args        []*CommandArg
+	handler     HandlerFunc
+	middlewares []Middleware
+}
+
+// NewCommand 创建命令定义，处理器通过 ctx.CommandArgs() 获取解析后的参数
+func NewCommand(name string, handler HandlerFunc) *Command {
+	return &Command{
+		Name:    name,
+		handler: handler,
+	}
+}
+
+// Describe 设置命令说明
+func (c *Command) Describe(description string) *Command {
+	c.Description = description
+	return c
+}
+
+// Alias 添加命令别名
+func (c *Command) Alias(aliases ...string) *Command {
+	c.Aliases = append(c.Aliases, aliases...)
+	return c
+}
+
+// Arg 添加必填的位置参数
+func (c *Command) Arg(name string, argType ArgType, help string) *Command {
+	c.Args = append(c.Args, &CommandArg{Name: name, Type: argType, Help: help, Required: true})
+	return c
+}
+
+// OptionalArg 添加可选的位置参数，def 为 nil 时未提供的参数不存在
+func (c *Command) OptionalArg(name string, argType ArgType, help string, def interface{}) *Command {
+	c.Args = append(c.Args, &CommandArg{Name: name, Type: argType, Help: help, Default: def})
+	return c
+}
+
+// Option 添加 --名称 形式的选项，可以写作 --名称 值 或 --名称=值
+func (c *Command) Option(name string, argType ArgType, help string, def interface{}) *Command {
+	c.Args = append(c.Args, &CommandArg{Name: name, Type: argType, Help: help, Named: true, Default: def})
+	return c
+}
+
+// Validate 为参数添加检查，参数不存在时 panic
+func (c *Command) Validate(name string, validate func(value interface{}) error) *Command {
+	arg := c.arg(name)
+	if arg == nil {
+		panic(fmt.Sprintf("命令 %s 没有参数 %s", c.Name, name))
+	}
+	arg.Validate = validate
+	return c
+}
+
+// Use 添加命令的中间件
+func (c *Command) Use(middleware Middleware) *Command {
+	c.middlewares = append(c.middlewares, middleware)
+	return c
+}
+
+// OneOf 检查文本参数是否为给定值之一
+func OneOf(values ...string) func(value interface{}) error {
+	return func(value interface{}) error {
+		for _, v := range values {
+			if value == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("可选值: %s", strings.Join(values, ", "))
+	}
+}
+
+// arg 根据名称查找参数
+func (c *Command) arg(name string) *CommandArg {
+	for _, arg := range c.Args {
+		if arg.Name == name {
+			return arg
+		}
+	}
+	return nil
+}
+
+// check 检查命令定义
+func (c *Command) check() error {
+	if c.Name == "" || strings.ContainsFunc(c.Name, unicode.IsSpace) {
+		return fmt.Errorf("%w: 命令名称 %q 无效", ErrInvalidCommand, c.Name)
+	}
+	if c.handler == nil {
+		return fmt.Errorf("%w: 命令 %s 没有处理器", ErrInvalidCommand, c.Name)
+	}
+
+	seen := make(map[string]bool, len(c.Args))
+	optional := false
+	rest := false
+	for _, arg := range c.Args {
+		if seen[arg.Name] {
+			return fmt.Errorf("%w: 命令 %s 的参数 %s 重复", ErrInvalidCommand, c.Name, arg.Name)
+		}
+		seen[arg.Name] = true
+
+		if arg.Default != nil && !defaultMatches(arg.Type, arg.Default) {
+			return fmt.Errorf("%w: 命令 %s 的参数 %s 的默认值类型应为%s", ErrInvalidCommand, c.Name, arg.Name, arg.Type)
+		}
+		if arg.Named {
+			if arg.Type == ArgRest {
+				return fmt.Errorf("%w: 命令 %s 的选项 %s 不能是剩余内容", ErrInvalidCommand, c.Name, arg.Name)
+			}
+			continue
+		}
+		if rest {
+			return fmt.Errorf("%w: 命令 %s 的剩余内容参数之后不能再有位置参数", ErrInvalidCommand, c.Name)
+		}
+		if arg.Required && optional {
+			return fmt.Errorf("%w: 命令 %s 的必填参数 %s 不能在可选参数之后", ErrInvalidCommand, c.Name, arg.Name)
+		}
+		optional = optional || !arg.Required
+		rest = arg.Type == ArgRest
+	}
+	return nil
+}
+
+// defaultMatches 检查默认值的类型是否与参数类型对应
+func defaultMatches(argType ArgType, value interface{}) bool {
+	switch argType {
+	case ArgInt:
+		_, ok := value.(int)
+		return ok
+	case ArgDuration:
+		_, ok := value.(time.Duration)
+		return ok
+	case ArgUser:
+		_, ok := value.(uint32)
+		return ok
+	case ArgImage:
+		_, ok := value.(*message.ImageElement)
+		return ok
+	default:
+		_, ok := value.(string)
+		return ok
+	}
+}
+
+// Usage 获取命令的用法，如 /remind <after> <content...> [--user @成员或QQ号]
+func (c *Command) Usage(prefix string) string {
+	var sb strings.Builder
+	sb.WriteString(prefix + c.Name)
+	for _, arg := range c.Args {
+		name := arg.Name
+		if arg.Type == ArgRest {
+			name += "..."
+		}
+		switch {
+		case arg.Named:
+			fmt.Fprintf(&sb, " [--%s %s]", arg.Name, arg.Type)
+		case arg.Required:
+			fmt.Fprintf(&sb, " <%s>", name)
+		default:
+			fmt.Fprintf(&sb, " [%s]", name)
+		}
+	}
+	return sb.String()
+}
+
+// Help 获取命令的详细帮助
+func (c *Command) Help(prefix string) string {
+	var sb strings.Builder
+	sb.WriteString("用法: " + c.Usage(prefix))
+	if c.Description != "" {
+		sb.WriteString("\n" + c.Description)
+	}
+	if len(c.Aliases) > 0 {
+		sb.WriteString("\n别名: " + strings.Join(c.Aliases, ", "))
+	}
+	for _, arg := range c.Args {
+		name := arg.Name
+		if arg.Named {
+			name = "--" + name
+		}
+		fmt.Fprintf(&sb, "\n  %s (%s)", name, arg.Type)
+		if arg.Help != "" {
+			sb.WriteString(" " + arg.Help)
+		}
+		if arg.Default != nil && arg.Default != "" {
+			fmt.Fprintf(&sb, "，默认 %v", arg.Default)
+		}
+	}
+	return sb.String()
+}
+
+// UsageError 命令参数错误，回复给用户时附带命令用法
+type UsageError struct {
+	Message string
+}
+
+func (e *UsageError) Error() string {
+	return e.Message
+}
+
+// Is 使 errors.Is(err, ErrUsage) 成立
+func (e *UsageError) Is(target error) bool {
+	return target == ErrUsage
+}
+
+// NewUsageError 创建命令参数错误，处理器返回该错误时向用户回复错误与命令用法
+func NewUsageError(format string, args ...interface{}) *UsageError {
+	return &UsageError{Message: fmt.Sprintf(format, args...)}
+}
+
+// CommandArgs 解析后的命令参数
+type CommandArgs struct {
+	values   map[string]interface{}
+	provided map[string]bool
+}
+
+// Get 获取参数的值，未提供且没有默认值时返回 false
+func (a *CommandArgs) Get(name string) (interface{}, bool) {
+	value, ok := a.values[name]
+	return value, ok
+}
+
+// Has 检查用户是否提供了参数
+func (a *CommandArgs) Has(name string) bool {
+	return a.provided[name]
+}
+
+// String 获取文本参数
+func (a *CommandArgs) String(name string) string {
+	value, _ := a.values[name].(string)
+	return value
+}
+
+// Int 获取整数参数
+func (a *CommandArgs) Int(name string) int {
+	value, _ := a.values[name].(int)
+	return value
+}
+
+// Duration 获取时长参数
+func (a *CommandArgs) Duration(name string) time.Duration {
+	value, _ := a.values[name].(time.Duration)
+	return value
+}
+
+// User 获取用户参数的QQ号
+func (a *CommandArgs) User(name string) uint32 {
+	value, _ := a.values[name].(uint32)
+	return value
+}
+
+// Image 获取图片参数
+func (a *CommandArgs) Image(name string) *message.ImageElement {
+	value, _ := a.values[name].(*message.ImageElement)
+	return value
+}
+
+// CommandArgs 获取通过 RegisterCommand 注册的命令解析后的参数，其他路由中返回空参数
+func (mc *MessageContext) CommandArgs() *CommandArgs {
+	if args, ok := mc.Metadata[commandArgsKey].(*CommandArgs); ok {
+		return args
+	}
+	return &CommandArgs{}
+}
+
+// commandToken 命令中的一个参数
+type commandToken struct {
+	text   string
+	quoted bool
+	at     *message.AtElement
+	image  *message.ImageElement
+	offset int // 在整条消息中的位置，用于获取剩余内容
+}
+
+// tokenize 将消息元素拆分为参数，文本按空白分隔并支持引号，@与图片各自作为一个参数
+// 返回的 raw 为整条消息的文本形式
+func tokenize(elements []message.IMessageElement) (tokens []commandToken, raw string) {
+	var sb strings.Builder
+	for _, element := range elements {
+		switch e := element.(type) {
+		case *message.TextElement:
+			tokens = append(tokens, tokenizeText(e.Content, sb.Len())...)
+			sb.WriteString(e.Content)
+		case *message.AtElement:
+			tokens = append(tokens, commandToken{at: e, offset: sb.Len()})
+			if e.Display != "" {
+				sb.WriteString(e.Display)
+			} else {
+				fmt.Fprintf(&sb, "@%d", e.TargetUin)
+			}
+		case *message.ImageElement:
+			tokens = append(tokens, commandToken{image: e, offset: sb.Len()})
+			sb.WriteString("[图片]")
+		}
+	}
+	return tokens, sb.String()
+}
+
+// tokenizeText 按空白拆分文本，引号中的空白不拆分
+func tokenizeText(text string, base int) []commandToken {
+	var tokens []commandToken
+	var current strings.Builder
+	var closing rune
+	start := -1
+	quoted := false
+
+	flush := func() {
+		if start >= 0 {
+			tokens = append(tokens, commandToken{text: current.String(), quoted: quoted, offset: base + start})
+		}
+		current.Reset()
+		start = -1
+		quoted = false
+	}
+
+	for i, r := range text {
+		switch {
+		case closing != 0:
+			if r == closing {
+				closing = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case unicode.IsSpace(r):
+			flush()
+		case start < 0 && (r == '"' || r == '\'' || r == '“'):
+			start = i
+			quoted = true
+			closing = r
+			if r == '“' {
+				closing = '”'
+			}
+		default:
+			if start < 0 {
+				start = i
+			}
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+// Parse 解析消息中的命令参数，参数错误时返回 *UsageError
+func (c *Command) Parse(ctx *MessageContext) (*CommandArgs, error) {
+	tokens, raw := tokenize(ctx.messageElements())
+	tokens = c.afterCommand(tokens, ctx.Config().CommandPrefix)
+
+	args := &CommandArgs{
+		values:   make(map[string]interface{}),
+		provided: make(map[string]bool),
+	}
+	positional := make([]*CommandArg, 0, len(c.Args))
+	for _, arg := range c.Args {
+		if !arg.Named {
+			positional = append(positional, arg)
+		}
+	}
+
+	next := 0
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		// --名称 值 或 --名称=值
+		if token.at == nil && token.image == nil && !token.quoted && len(token.text) > 2 && strings.HasPrefix(token.text, "--") {
+			name, value, hasValue := strings.Cut(token.text[2:], "=")
+			arg := c.arg(name)
+			if arg == nil || !arg.Named {
+				return nil, NewUsageError("未知的选项 --%s", name)
+			}
+			valueToken := commandToken{text: value}
+			if !hasValue {
+				if i+1 >= len(tokens) {
+					return nil, NewUsageError("选项 --%s 缺少值", name)
+				}
+				i++
+				valueToken = tokens[i]
+			}
+			if err := args.set(arg, valueToken); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if next >= len(positional) {
+			return nil, NewUsageError("多余的参数 %s", strings.TrimSpace(raw[token.offset:]))
+		}
+		arg := positional[next]
+		next++
+		if arg.Type == ArgRest {
+			token = commandToken{text: strings.TrimSpace(raw[token.offset:])}
+			if err := args.set(arg, token); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if err := args.set(arg, token); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, arg := range c.Args {
+		if args.provided[arg.Name] {
+			continue
+		}
+		if arg.Required && !arg.Named {
+			return nil, NewUsageError("缺少参数 %s", arg.Name)
+		}
+		if arg.Default != nil {
+			args.values[arg.Name] = arg.Default
+		}
+	}
+	return args, nil
+}
+
+// afterCommand 获取命令之后的参数，跳过命令本身以及之前的图片、@等元素
+// 如 "[图片]/plugin list"、"@机器人 /remind 10m 喝水"；找不到命令时所有元素都视为参数
+func (c *Command) afterCommand(tokens []commandToken, prefix string) []commandToken {
+	for i, token := range tokens {
+		if token.at != nil || token.image != nil || token.quoted || !strings.HasPrefix(token.text, prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(token.text, prefix)
+		if name == c.Name || containsString(c.Aliases, name) {
+			return tokens[i+1:]
+		}
+	}
+	return tokens
+}
+
+// set 转换并检查参数的值
+func (a *CommandArgs) set(arg *CommandArg, token commandToken) error {
+	value, err := convertArg(arg.Type, token)
+	if err != nil {
+		return NewUsageError("参数 %s %v", arg.Name, err)
+	}
+	if arg.Validate != nil {
+		if err := arg.Validate(value); err != nil {
+			return NewUsageError("参数 %s 无效: %v", arg.Name, err)
+		}
+	}
+	a.values[arg.Name] = value
+	a.provided[arg.Name] = true
+	return nil
+}
+
+// convertArg 将参数转换为参数类型对应的值
+func convertArg(argType ArgType, token commandToken) (interface{}, error) {
+	if argType == ArgImage {
+		if token.image == nil {
+			return nil, errors.New("需要图片")
+		}
+		return token.image, nil
+	}
+	if argType == ArgUser && token.at != nil {
+		if token.at.TargetUin == 0 {
+			return nil, errors.New("不能是@全体成员")
+		}
+		return token.at.TargetUin, nil
+	}
+	if token.at != nil || token.image != nil {
+		return nil, fmt.Errorf("需要%s", argType)
+	}
+
+	switch argType {
+	case ArgInt:
+		value, err := strconv.Atoi(token.text)
+		if err != nil {
+			return nil, fmt.Errorf("需要整数，收到 %q", token.text)
+		}
+		return value, nil
+	case ArgDuration:
+		if seconds, err := strconv.Atoi(token.text); err == nil {
+			return time.Duration(seconds) * time.Second, nil
+		}
+		value, err := time.ParseDuration(token.text)
+		if err != nil {
+			return nil, fmt.Errorf("需要时长（如 10m、1h30m），收到 %q", token.text)
+		}
+		return value, nil
+	case ArgUser:
+		value, err := strconv.ParseUint(token.text, 10, 32)
+		if err != nil || value == 0 {
+			return nil, fmt.Errorf("需要@成员或QQ号，收到 %q", token.text)
+		}
+		return uint32(value), nil
+	default:
+		return token.text, nil
+	}
+}
+
+// handle 解析参数后执行处理器，参数错误时回复错误与用法
+func (c *Command) handle(ctx *MessageContext) error {
+	args, err := c.Parse(ctx)
+	if err == nil {
+		ctx.Set(commandArgsKey, args)
+		err = c.handler(ctx)
+	}
+
+	var usageErr *UsageError
+	if !errors.As(err, &usageErr) {
+		return err
+	}
+	_, err = ctx.ReplyText(fmt.Sprintf("%s\n用法: %s", usageErr.Message, c.Usage(ctx.Config().CommandPrefix)))
+	return err
+}
+
+// RegisterCommand 注册命令定义，命令前缀使用当前群与发送者配置的 commandPrefix
+func (lm *LogicManager) RegisterCommand(cmd *Command) (string, error) {
+	if err := cmd.check(); err != nil {
+		return "", err
+	}
+
+	route := NewRoute("command_"+cmd.Name, NewHandlerAdapter(cmd.handle))
+	route.SetPriority(PriorityCommand).SetConsume(true)
+	route.Match(NewScopedCommandMatcher(append([]string{cmd.Name}, cmd.Aliases...)...))
+	for _, middleware := range cmd.middlewares {
+		route.Use(middleware)
+	}
+	route.Command = cmd
+	return lm.Register(route)
+}
+
+// AddCommand 注册命令定义，失败时记录错误并返回空ID
+func (lm *LogicManager) AddCommand(cmd *Command) string {
+	id, err := lm.RegisterCommand(cmd)
+	if err != nil {
+		utils.Errorf("注册命令 %s 失败: %v", cmd.Name, err)
+	}
+	return id
+}
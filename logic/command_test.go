@@ -0,0 +1,120 @@
+package logic
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/LagrangeDev/LagrangeGo/message"
+	"github.com/vintcessun/WE-Assistant/config"
+)
+
+func newCommandTestContext(elements ...message.IMessageElement) *MessageContext {
+	ctx := NewMessageContext(nil, &message.GroupMessage{
+		GroupUin: 1,
+		Sender:   &message.Sender{Uin: 2},
+		Elements: elements,
+	})
+	ctx.cfg = config.Default()
+	return ctx
+}
+
+func newRemindCommand() *Command {
+	return NewCommand("remind", nil).
+		Alias("提醒").
+		Arg("delay", ArgDuration, "延迟").
+		Arg("content", ArgRest, "内容")
+}
+
+func TestCommandParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements []message.IMessageElement
+	}{
+		{"plain", []message.IMessageElement{message.NewText("/remind 10m 喝水 吃饭")}},
+		{"alias", []message.IMessageElement{message.NewText("/提醒 10m 喝水 吃饭")}},
+		{"after at", []message.IMessageElement{message.NewAt(10001, "@机器人"), message.NewText(" /remind 10m 喝水 吃饭")}},
+		{"after image", []message.IMessageElement{&message.ImageElement{}, message.NewText("/remind 10m 喝水 吃饭")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := newRemindCommand().Parse(newCommandTestContext(tt.elements...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := args.Duration("delay"); got != 10*time.Minute {
+				t.Errorf("delay = %v, want 10m", got)
+			}
+			if got := args.String("content"); got != "喝水 吃饭" {
+				t.Errorf("content = %q, want %q", got, "喝水 吃饭")
+			}
+		})
+	}
+}
+
+func TestCommandParseUsageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"missing", "/remind"},
+		{"invalid duration", "/remind soon 喝水"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRemindCommand().Parse(newCommandTestContext(message.NewText(tt.text)))
+			if !errors.Is(err, ErrUsage) {
+				t.Errorf("expected a usage error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCommandParseOptions(t *testing.T) {
+	cmd := NewCommand("plugin", nil).
+		Arg("action", ArgString, "操作").
+		Option("group", ArgInt, "群号", 0)
+
+	args, err := cmd.Parse(newCommandTestContext(message.NewText("/plugin list --group=123")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args.String("action") != "list" || args.Int("group") != 123 {
+		t.Errorf("unexpected args action=%q group=%d", args.String("action"), args.Int("group"))
+	}
+
+	if _, err := cmd.Parse(newCommandTestContext(message.NewText("/plugin list --unknown 1"))); !errors.Is(err, ErrUsage) {
+		t.Errorf("expected a usage error for an unknown option, got %v", err)
+	}
+}
+
+func TestRegisterCommandDispatch(t *testing.T) {
+	lm := NewLogicManager()
+	var contents []string
+	cmd := NewCommand("remind", func(ctx *MessageContext) error {
+		contents = append(contents, ctx.CommandArgs().String("content"))
+		return nil
+	}).
+		Alias("提醒").
+		Arg("delay", ArgDuration, "延迟").
+		Arg("content", ArgRest, "内容")
+	if _, err := lm.RegisterCommand(cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := [][]message.IMessageElement{
+		{message.NewText("/remind 10m 喝水")},
+		{message.NewAt(10001, "@机器人"), message.NewText(" /remind 10m 喝水")},
+		{message.NewAt(10001, "@机器人"), message.NewText(" "), &message.ImageElement{}, message.NewText("/提醒 10m 喝水")},
+		{message.NewText("喝水 /remind 10m")},
+		{message.NewAt(10001, "@机器人")},
+	}
+	for _, elements := range messages {
+		lm.GetRouter().Handle(newCommandTestContext(elements...))
+	}
+
+	if want := []string{"喝水", "喝水", "喝水"}; !reflect.DeepEqual(contents, want) {
+		t.Errorf("contents = %v, want %v", contents, want)
+	}
+}
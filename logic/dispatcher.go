@@ -0,0 +1,310 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/vintcessun/WE-Assistant/utils"
+)
+
+// 消息分发: 固定数量的工作协程处理消息，同一会话（同一账号下的同一个群或私聊用户）的消息按到达顺序依次处理，
+// 不同会话的消息并行处理。队列已满时按 QueuePolicy 阻塞等待或丢弃新消息。
+// 处理器等待多轮会话的回复时让出会话与工作协程，同一会话之后的消息可以继续处理。
+
+// QueuePolicy 队列已满时的处理策略
+type QueuePolicy string
+
+const (
+	// QueuePolicyBlock 阻塞等待队列有空位，压力传导给消息来源
+	QueuePolicyBlock QueuePolicy = "block"
+	// QueuePolicyDrop 丢弃新消息
+	QueuePolicyDrop QueuePolicy = "drop"
+)
+
+var (
+	// ErrQueueFull 队列已满，消息被丢弃
+	ErrQueueFull = errors.New("消息队列已满")
+	// ErrDispatcherStopped 分发器已停止
+	ErrDispatcherStopped = errors.New("消息分发器已停止")
+)
+
+// DispatcherConfig 消息分发配置
+type DispatcherConfig struct {
+	Workers   int         // 工作协程数量
+	QueueSize int         // 等待处理的消息数量上限
+	Policy    QueuePolicy // 队列已满时的处理策略
+}
+
+// DefaultDispatcherConfig 默认消息分发配置
+func DefaultDispatcherConfig() *DispatcherConfig {
+	return &DispatcherConfig{
+		Workers:   8,
+		QueueSize: 1000,
+		Policy:    QueuePolicyBlock,
+	}
+}
+
+// DispatcherStats 消息分发统计
+type DispatcherStats struct {
+	Workers       int    // 工作协程数量
+	Capacity      int    // 队列容量
+	Queued        int    // 等待处理的消息数量
+	MaxQueued     int    // 等待处理的消息数量峰值
+	Running       int    // 正在处理的消息数量
+	Waiting       int    // 正在等待多轮会话回复的消息数量
+	Conversations int    // 有消息等待或正在处理的会话数量
+	Submitted     uint64 // 进入队列的消息总数
+	Processed     uint64 // 处理完成的消息总数
+	Dropped       uint64 // 因队列已满被丢弃的消息总数
+	Claimed       uint64 // 被等待回复的多轮会话直接取走的消息总数
+}
+
+// conversation 单个会话中等待处理的消息
+// 会话存在时，要么在 ready 中等待工作协程，要么正在被某个工作协程处理，保证同一会话的消息依次处理
+type conversation struct {
+	key   string
+	queue []*MessageContext
+}
+
+// Dispatcher 有界的消息分发器
+type Dispatcher struct {
+	config        *DispatcherConfig
+	handle        HandlerFunc
+	conversations map[string]*conversation
+	ready         []*conversation
+	stats         DispatcherStats
+	started       bool
+	stopped       bool
+	mu            sync.Mutex
+	hasWork       *sync.Cond
+	notFull       *sync.Cond
+	wg            sync.WaitGroup
+	logger        utils.Logger
+}
+
+// NewDispatcher 创建消息分发器，handle 的错误只记录日志
+func NewDispatcher(config *DispatcherConfig, handle HandlerFunc) *Dispatcher {
+	if config == nil {
+		config = DefaultDispatcherConfig()
+	}
+	d := &Dispatcher{
+		config:        config,
+		handle:        handle,
+		conversations: make(map[string]*conversation),
+		logger:        utils.GetLogger().WithField("module", "dispatcher"),
+	}
+	d.hasWork = sync.NewCond(&d.mu)
+	d.notFull = sync.NewCond(&d.mu)
+	d.stats.Workers = config.Workers
+	d.stats.Capacity = config.QueueSize
+	return d
+}
+
+// Start 启动工作协程，重复调用无效果
+func (d *Dispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.started || d.stopped {
+		return
+	}
+	d.started = true
+
+	workers := d.config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+}
+
+// Submit 将消息放入所属会话的队列
+// 队列已满时按策略阻塞等待或返回 ErrQueueFull，分发器停止后返回 ErrDispatcherStopped
+func (d *Dispatcher) Submit(ctx *MessageContext) error {
+	key := conversationKey(ctx)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for !d.stopped && d.stats.Queued >= d.config.QueueSize {
+		if d.config.Policy == QueuePolicyDrop {
+			d.stats.Dropped++
+			return fmt.Errorf("%w (容量 %d)", ErrQueueFull, d.config.QueueSize)
+		}
+		d.notFull.Wait()
+	}
+	if d.stopped {
+		return ErrDispatcherStopped
+	}
+
+	conv, ok := d.conversations[key]
+	if !ok {
+		conv = &conversation{key: key}
+		d.conversations[key] = conv
+		d.ready = append(d.ready, conv)
+		d.hasWork.Signal()
+	}
+	conv.queue = append(conv.queue, ctx)
+
+	d.stats.Submitted++
+	d.stats.Queued++
+	if d.stats.Queued > d.stats.MaxQueued {
+		d.stats.MaxQueued = d.stats.Queued
+	}
+	return nil
+}
+
+// worker 依次取出有消息等待的会话，每次处理其中一条消息
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+
+	for {
+		d.mu.Lock()
+		for len(d.ready) == 0 && !d.stopped {
+			d.hasWork.Wait()
+		}
+		if len(d.ready) == 0 {
+			d.mu.Unlock()
+			return
+		}
+
+		conv := d.ready[0]
+		d.ready = d.ready[1:]
+		ctx := conv.queue[0]
+		conv.queue = conv.queue[1:]
+		d.stats.Queued--
+		d.stats.Running++
+		d.notFull.Signal()
+
+		var detached, finished bool
+		ctx.detach = func() {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			if detached || finished {
+				return
+			}
+			detached = true
+			d.stats.Running--
+			d.stats.Waiting++
+			d.release(conv)
+			// 由新的工作协程继续处理，当前协程在处理器返回后退出
+			d.wg.Add(1)
+			go d.worker()
+		}
+		d.mu.Unlock()
+
+		d.process(ctx)
+
+		d.mu.Lock()
+		finished = true
+		d.stats.Processed++
+		if detached {
+			d.stats.Waiting--
+			d.mu.Unlock()
+			return
+		}
+		d.stats.Running--
+		d.release(conv)
+		d.mu.Unlock()
+	}
+}
+
+// take 从会话的队列中取出第一条满足条件的消息，取出的消息不再由分发器处理，没有时返回 nil
+func (d *Dispatcher) take(key string, match func(*MessageContext) bool) *MessageContext {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	conv, ok := d.conversations[key]
+	if !ok {
+		return nil
+	}
+	for i, ctx := range conv.queue {
+		if !match(ctx) {
+			continue
+		}
+		conv.queue = append(conv.queue[:i:i], conv.queue[i+1:]...)
+		d.stats.Queued--
+		d.stats.Claimed++
+		d.notFull.Signal()
+		// 等待工作协程的会话已没有消息时移除，正在处理的会话由 release 移除
+		if len(conv.queue) == 0 {
+			for j, c := range d.ready {
+				if c == conv {
+					d.ready = append(d.ready[:j:j], d.ready[j+1:]...)
+					delete(d.conversations, key)
+					break
+				}
+			}
+		}
+		return ctx
+	}
+	return nil
+}
+
+// release 结束会话当前消息的处理，还有消息时重新等待工作协程，调用方需持有锁
+func (d *Dispatcher) release(conv *conversation) {
+	if len(conv.queue) > 0 {
+		d.ready = append(d.ready, conv)
+		d.hasWork.Signal()
+	} else {
+		delete(d.conversations, conv.key)
+	}
+}
+
+// process 处理单条消息，panic 不影响工作协程
+func (d *Dispatcher) process(ctx *MessageContext) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.logger.Errorf("处理消息时发生panic: %v", r)
+		}
+	}()
+	if err := d.handle(ctx); err != nil {
+		d.logger.Errorf("处理消息失败: %v", err)
+	}
+}
+
+// Stop 停止接收新消息，处理完队列中剩余的消息后停止工作协程，超过截止时间后不再等待
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.mu.Lock()
+	d.stopped = true
+	d.hasWork.Broadcast()
+	d.notFull.Broadcast()
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("等待消息分发器停止超时: %w", ctx.Err())
+	}
+}
+
+// Stats 获取分发统计
+func (d *Dispatcher) Stats() DispatcherStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	stats := d.stats
+	stats.Conversations = len(d.conversations)
+	return stats
+}
+
+// conversationKey 获取消息所属的会话，同一会话的消息依次处理
+func conversationKey(ctx *MessageContext) string {
+	var account uint32
+	if ctx.Client != nil {
+		account = ctx.Account()
+	}
+	if groupUin := ctx.GroupUin(); groupUin != 0 {
+		return fmt.Sprintf("%d:group:%d", account, groupUin)
+	}
+	return fmt.Sprintf("%d:user:%d", account, ctx.SenderUin())
+}
@@ -0,0 +1,300 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LagrangeDev/LagrangeGo/message"
+)
+
+// newDispatchTestMessage 创建指定群中的文本消息
+func newDispatchTestMessage(group uint32, text string) *MessageContext {
+	return NewMessageContext(nil, &message.GroupMessage{
+		GroupUin: group,
+		Sender:   &message.Sender{Uin: 2},
+		Elements: []message.IMessageElement{message.NewText(text)},
+	})
+}
+
+// newBlockingDispatcher 创建处理器阻塞到 release 关闭的分发器，started 在每条消息开始处理时收到消息文本
+func newBlockingDispatcher(t *testing.T, config *DispatcherConfig) (d *Dispatcher, started chan string, release chan struct{}) {
+	t.Helper()
+	started = make(chan string, 100)
+	release = make(chan struct{})
+	d = NewDispatcher(config, func(ctx *MessageContext) error {
+		started <- ctx.GetMessageText()
+		<-release
+		return nil
+	})
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+		d.Stop(context.Background())
+	})
+	return d, started, release
+}
+
+func waitStarted(t *testing.T, started chan string) string {
+	t.Helper()
+	select {
+	case text := <-started:
+		return text
+	case <-time.After(2 * time.Second):
+		t.Fatal("message was not processed")
+		return ""
+	}
+}
+
+// checkStats 检查统计数据没有漂移
+func checkStats(t *testing.T, stats DispatcherStats) {
+	t.Helper()
+	if stats.Submitted != stats.Processed+stats.Claimed+uint64(stats.Queued+stats.Running+stats.Waiting) {
+		t.Errorf("stats drift: %+v", stats)
+	}
+}
+
+func TestDispatcherConversationOrder(t *testing.T) {
+	var mu sync.Mutex
+	got := make(map[uint32][]string)
+	running := make(map[uint32]bool)
+	d := NewDispatcher(&DispatcherConfig{Workers: 4, QueueSize: 100, Policy: QueuePolicyBlock}, func(ctx *MessageContext) error {
+		group := ctx.GroupUin()
+		mu.Lock()
+		if running[group] {
+			t.Errorf("group %d processed concurrently", group)
+		}
+		running[group] = true
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running[group] = false
+		got[group] = append(got[group], ctx.GetMessageText())
+		mu.Unlock()
+		return nil
+	})
+	d.Start()
+
+	want := make(map[uint32][]string)
+	for i := 0; i < 20; i++ {
+		for _, group := range []uint32{1, 2, 3} {
+			text := fmt.Sprint(i)
+			want[group] = append(want[group], text)
+			if err := d.Submit(newDispatchTestMessage(group, text)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := d.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	stats := d.Stats()
+	if stats.Processed != 60 || stats.Conversations != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+	checkStats(t, stats)
+}
+
+func TestDispatcherParallelConversations(t *testing.T) {
+	d, started, _ := newBlockingDispatcher(t, &DispatcherConfig{Workers: 2, QueueSize: 10, Policy: QueuePolicyBlock})
+	d.Start()
+
+	d.Submit(newDispatchTestMessage(1, "a"))
+	d.Submit(newDispatchTestMessage(1, "b"))
+	d.Submit(newDispatchTestMessage(2, "c"))
+
+	// 两个会话同时处理，同一会话的第二条消息等待
+	first, second := waitStarted(t, started), waitStarted(t, started)
+	if first == second || (first != "a" && first != "c") || (second != "a" && second != "c") {
+		t.Errorf("started %q and %q, want a and c", first, second)
+	}
+	stats := d.Stats()
+	if stats.Running != 2 || stats.Queued != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	checkStats(t, stats)
+}
+
+func TestDispatcherDropPolicy(t *testing.T) {
+	d, started, release := newBlockingDispatcher(t, &DispatcherConfig{Workers: 1, QueueSize: 1, Policy: QueuePolicyDrop})
+	d.Start()
+
+	d.Submit(newDispatchTestMessage(1, "a"))
+	waitStarted(t, started)
+	if err := d.Submit(newDispatchTestMessage(1, "b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Submit(newDispatchTestMessage(2, "c")); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+	if stats := d.Stats(); stats.Dropped != 1 || stats.Submitted != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	close(release)
+	if err := d.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	stats := d.Stats()
+	if stats.Processed != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+	checkStats(t, stats)
+}
+
+func TestDispatcherBlockPolicy(t *testing.T) {
+	d, started, release := newBlockingDispatcher(t, &DispatcherConfig{Workers: 1, QueueSize: 1, Policy: QueuePolicyBlock})
+	d.Start()
+
+	d.Submit(newDispatchTestMessage(1, "a"))
+	waitStarted(t, started)
+	d.Submit(newDispatchTestMessage(1, "b"))
+
+	submitted := make(chan error, 1)
+	go func() {
+		submitted <- d.Submit(newDispatchTestMessage(2, "c"))
+	}()
+	select {
+	case err := <-submitted:
+		t.Fatalf("submit should block while the queue is full, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-submitted:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("submit did not resume after the queue drained")
+	}
+	if err := d.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if stats := d.Stats(); stats.Processed != 3 || stats.Dropped != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestDispatcherStopDrainsQueue(t *testing.T) {
+	var processed []string
+	d := NewDispatcher(&DispatcherConfig{Workers: 1, QueueSize: 10, Policy: QueuePolicyBlock}, func(ctx *MessageContext) error {
+		processed = append(processed, ctx.GetMessageText())
+		return nil
+	})
+	for _, text := range []string{"a", "b", "c"} {
+		if err := d.Submit(newDispatchTestMessage(1, text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Start()
+
+	if err := d.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(processed, []string{"a", "b", "c"}) {
+		t.Errorf("processed = %v", processed)
+	}
+	if err := d.Submit(newDispatchTestMessage(1, "d")); !errors.Is(err, ErrDispatcherStopped) {
+		t.Errorf("expected ErrDispatcherStopped, got %v", err)
+	}
+}
+
+func TestDispatcherStopDeadline(t *testing.T) {
+	d, started, _ := newBlockingDispatcher(t, &DispatcherConfig{Workers: 1, QueueSize: 10, Policy: QueuePolicyBlock})
+	d.Start()
+	d.Submit(newDispatchTestMessage(1, "a"))
+	waitStarted(t, started)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+}
+
+func TestDispatcherDetach(t *testing.T) {
+	d, started, release := newBlockingDispatcher(t, &DispatcherConfig{Workers: 1, QueueSize: 10, Policy: QueuePolicyBlock})
+	d.handle = func(ctx *MessageContext) error {
+		text := ctx.GetMessageText()
+		if text == "a" {
+			ctx.detach()
+			ctx.detach()
+		}
+		started <- text
+		<-release
+		return nil
+	}
+	d.Start()
+
+	d.Submit(newDispatchTestMessage(1, "a"))
+	d.Submit(newDispatchTestMessage(1, "b"))
+
+	// a 让出会话与工作协程后，同一会话中的 b 在只有一个工作协程时也能处理
+	waitStarted(t, started)
+	waitStarted(t, started)
+	stats := d.Stats()
+	if stats.Waiting != 1 || stats.Running != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	checkStats(t, stats)
+
+	close(release)
+	if err := d.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	stats = d.Stats()
+	if stats.Processed != 2 || stats.Waiting != 0 || stats.Running != 0 || stats.Conversations != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestDispatcherTake(t *testing.T) {
+	d, started, release := newBlockingDispatcher(t, &DispatcherConfig{Workers: 1, QueueSize: 10, Policy: QueuePolicyBlock})
+	d.Start()
+
+	d.Submit(newDispatchTestMessage(1, "a"))
+	waitStarted(t, started)
+	d.Submit(newDispatchTestMessage(1, "b"))
+	d.Submit(newDispatchTestMessage(2, "c"))
+
+	key := conversationKey(newDispatchTestMessage(2, ""))
+	isText := func(text string) func(*MessageContext) bool {
+		return func(ctx *MessageContext) bool { return ctx.GetMessageText() == text }
+	}
+	if ctx := d.take(key, isText("b")); ctx != nil {
+		t.Error("take should only look in the given conversation")
+	}
+	if ctx := d.take(key, isText("c")); ctx == nil || ctx.GetMessageText() != "c" {
+		t.Fatalf("take = %v", ctx)
+	}
+	stats := d.Stats()
+	if stats.Claimed != 1 || stats.Queued != 1 || stats.Conversations != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	checkStats(t, stats)
+
+	close(release)
+	if err := d.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	stats = d.Stats()
+	if stats.Processed != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+	checkStats(t, stats)
+}
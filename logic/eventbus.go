@@ -2,10 +2,13 @@ package logic
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"github.com/vintcessun/WE-Assistant/config"
 )
 
 // Event 事件接口
@@ -166,6 +169,26 @@ func (bus *EventBus) Close() {
 	logrus.Info("事件总线已关闭")
 }
 
+// Shutdown 等待异步事件处理器结束后关闭事件总线
+// ctx 结束时取消仍在运行的处理器并返回错误
+func (bus *EventBus) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		bus.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		bus.cancel()
+		logrus.Info("事件总线已关闭")
+		return nil
+	case <-ctx.Done():
+		bus.cancel()
+		return fmt.Errorf("等待事件处理器结束超时: %w", ctx.Err())
+	}
+}
+
 // GetSubscriberCount 获取订阅者数量
 func (bus *EventBus) GetSubscriberCount(eventType string) int {
 	bus.mu.RLock()
@@ -207,6 +230,8 @@ const (
 	EventTypeUserJoined       = "user.joined"
 	EventTypeUserLeft         = "user.left"
 	EventTypeError            = "error.occurred"
+	EventTypeSigRefreshed     = "sig.refreshed"
+	EventTypeConfigReloaded   = "config.reloaded"
 )
 
 // 全局事件总线实例
@@ -231,11 +256,50 @@ func PublishCommandExecuted(ctx *MessageContext, command string) {
 	GlobalEventBus.Publish(event)
 }
 
-// PublishError 发布错误事件
+// SigRefreshedEvent 签名刷新事件
+type SigRefreshedEvent struct {
+	*BaseEvent
+	Account uint32
+}
+
+// PublishSigRefreshed 发布签名刷新事件
+func PublishSigRefreshed(account uint32) {
+	event := &SigRefreshedEvent{
+		BaseEvent: NewEvent(EventTypeSigRefreshed, account),
+		Account:   account,
+	}
+	GlobalEventBus.Publish(event)
+}
+
+// ConfigReloadedEvent 配置重新加载事件
+type ConfigReloadedEvent struct {
+	*BaseEvent
+	Old     *config.Config
+	New     *config.Config
+	Changes []config.Change
+}
+
+// PublishConfigReloaded 发布配置重新加载事件
+func PublishConfigReloaded(oldConfig, newConfig *config.Config, changes []config.Change) {
+	event := &ConfigReloadedEvent{
+		BaseEvent: NewEvent(EventTypeConfigReloaded, changes),
+		Old:       oldConfig,
+		New:       newConfig,
+		Changes:   changes,
+	}
+	GlobalEventBus.Publish(event)
+}
+
+// PublishError 发布错误事件，处理超时时 timeout 为 true 并带有超时的路由ID
 func PublishError(err error, ctx *MessageContext) {
 	data := map[string]interface{}{
 		"error":   err.Error(),
 		"context": ctx,
+		"timeout": errors.Is(err, ErrHandlerTimeout),
+	}
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		data["route"] = timeoutErr.RouteID
 	}
 	event := NewEvent(EventTypeError, data)
 	GlobalEventBus.Publish(event)
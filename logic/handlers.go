@@ -16,17 +16,8 @@ func (h *EchoHandler) Handle(ctx *MessageContext) error {
 		return nil
 	}
 
-	if privateMsg, ok := ctx.GetPrivateMessage(); ok {
-		ctx.Client.SendPrivateMessage(privateMsg.Sender.Uin, []message.IMessageElement{
-			message.NewText("你说了: " + text),
-		})
-	} else if groupMsg, ok := ctx.GetGroupMessage(); ok {
-		ctx.Client.SendGroupMessage(groupMsg.GroupUin, []message.IMessageElement{
-			message.NewText("你说了: " + text),
-		})
-	}
-
-	return nil
+	_, err := ctx.ReplyText("你说了: " + text)
+	return err
 }
 */
 
@@ -35,19 +26,8 @@ func (h *EchoHandler) Handle(ctx *MessageContext) error {
 type PingHandler struct{}
 
 func (h *PingHandler) Handle(ctx *MessageContext) error {
-	response := "pong!"
-
-	if privateMsg, ok := ctx.GetPrivateMessage(); ok {
-		ctx.Client.SendPrivateMessage(privateMsg.Sender.Uin, []message.IMessageElement{
-			message.NewText(response),
-		})
-	} else if groupMsg, ok := ctx.GetGroupMessage(); ok {
-		ctx.Client.SendGroupMessage(groupMsg.GroupUin, []message.IMessageElement{
-			message.NewText(response),
-		})
-	}
-
-	return nil
+	_, err := ctx.ReplyText("pong!")
+	return err
 }
 */
 
@@ -61,17 +41,8 @@ func (h *HelpHandler) Handle(ctx *MessageContext) error {
 /help - 显示帮助
 /echo <消息> - 回声消息`
 
-	if privateMsg, ok := ctx.GetPrivateMessage(); ok {
-		ctx.Client.SendPrivateMessage(privateMsg.Sender.Uin, []message.IMessageElement{
-			message.NewText(help),
-		})
-	} else if groupMsg, ok := ctx.GetGroupMessage(); ok {
-		ctx.Client.SendGroupMessage(groupMsg.GroupUin, []message.IMessageElement{
-			message.NewText(help),
-		})
-	}
-
-	return nil
+	_, err := ctx.ReplyText(help)
+	return err
 }
 */
 
@@ -89,90 +60,112 @@ func (h *FriendRequestHandler) Handle(ctx *MessageContext) error {
 }
 */
 
-// RegisterCustomLogic 注册所有自定义逻辑
-func RegisterCustomLogic() {
-	if Manager == nil {
-		utils.Error("LogicManager 未初始化")
-		return
-	}
+func init() {
+	RegisterPlugin(&MessageLogPlugin{})
+}
 
-	// 注册ping命令
-	/*
-		Manager.HandleCommand("/", "ping", func(ctx *MessageContext) error {
-			handler := &PingHandler{}
-			return handler.Handle(ctx)
-		})
-	*/
+// MessageLogPlugin 记录收到的群聊与私聊消息
+type MessageLogPlugin struct{}
 
-	// 注册help命令
-	/*
-		Manager.HandleCommand("/", "help", func(ctx *MessageContext) error {
-			handler := &HelpHandler{}
-			return handler.Handle(ctx)
-		})
-	*/
+func (p *MessageLogPlugin) Name() string {
+	return "message_log"
+}
 
-	// 注册echo命令
-	/*
-		Manager.HandleCommand("/", "echo", func(ctx *MessageContext) error {
-			handler := &EchoHandler{}
-			return handler.Handle(ctx)
-		})
-	*/
+func (p *MessageLogPlugin) Version() string {
+	return "1.0.0"
+}
 
-	// 注册好友请求处理
-	/*
-		Manager.HandleFriendRequest(func(ctx *MessageContext) error {
-			handler := &FriendRequestHandler{}
-			return handler.Handle(ctx)
-		})
-	*/
+func (p *MessageLogPlugin) Dependencies() []string {
+	return nil
+}
 
-	// 注册基于文本匹配的处理器
-	/*
-		Manager.HandleGroupMessage(func(ctx *MessageContext) error {
-			text := ctx.GetMessageText()
-			if text == "hello" {
-				if groupMsg, ok := ctx.GetGroupMessage(); ok {
-					ctx.Client.SendGroupMessage(groupMsg.GroupUin, []message.IMessageElement{
-						message.NewText("Hello World!"),
-					})
-				}
-			}
-			return nil
-		}, NewTextMatcher("hello", false))
-	*/
-
-	Manager.HandleGroupMessage(func(ctx *MessageContext) error {
+func (p *MessageLogPlugin) Init(manager *LogicManager) error {
+	manager.HandleGroupMessage(func(ctx *MessageContext) error {
 		return HandleGroupMessage(ctx)
 	})
-	Manager.HandlePrivateMessage(func(ctx *MessageContext) error {
+	manager.HandlePrivateMessage(func(ctx *MessageContext) error {
 		return HandlePrivateMessage((ctx))
 	})
 
-	// 注册私聊消息处理
-	/*
-		Manager.HandlePrivateMessage(func(ctx *MessageContext) error {
-			text := ctx.GetMessageText()
-			if text == "hello" {
-				if privateMsg, ok := ctx.GetPrivateMessage(); ok {
-					ctx.Client.SendPrivateMessage(privateMsg.Sender.Uin, []message.IMessageElement{
-						message.NewText("Hello! 这是私聊回复"),
-					})
-				}
-			}
-			return nil
-		}, NewTextMatcher("hello", false))
-	*/
-
 	// 注册事件监听器
-	Manager.GetEventBus().Subscribe(EventTypeCommandExecuted, func(ctx context.Context, event Event) error {
+	manager.Subscribe(EventTypeCommandExecuted, func(ctx context.Context, event Event) error {
 		if msgEvent, ok := event.(*MessageEvent); ok {
 			command := msgEvent.MessageContext.GetString("executed_command")
 			utils.Infof("命令 %s 已执行", command)
 		}
 		return nil
 	})
+	return nil
+}
+
+func (p *MessageLogPlugin) Shutdown() error {
+	return nil
+}
+
+// ExamplePlugin 示例插件，注册上面的示例处理器
+/*
+func init() {
+	RegisterPlugin(&ExamplePlugin{})
+}
+
+type ExamplePlugin struct{}
+
+func (p *ExamplePlugin) Name() string           { return "example" }
+func (p *ExamplePlugin) Version() string        { return "1.0.0" }
+func (p *ExamplePlugin) Dependencies() []string { return nil }
+func (p *ExamplePlugin) Shutdown() error        { return nil }
+
+func (p *ExamplePlugin) Init(manager *LogicManager) error {
+	// 注册ping命令
+	manager.HandleCommand("/", "ping", func(ctx *MessageContext) error {
+		handler := &PingHandler{}
+		return handler.Handle(ctx)
+	})
+
+	// 注册help命令
+	manager.HandleCommand("/", "help", func(ctx *MessageContext) error {
+		handler := &HelpHandler{}
+		return handler.Handle(ctx)
+	})
+
+	// 注册echo命令
+	manager.HandleCommand("/", "echo", func(ctx *MessageContext) error {
+		handler := &EchoHandler{}
+		return handler.Handle(ctx)
+	})
+
+	// 注册好友请求处理
+	manager.HandleFriendRequest(func(ctx *MessageContext) error {
+		handler := &FriendRequestHandler{}
+		return handler.Handle(ctx)
+	})
+
+	// 注册基于文本匹配的处理器
+	manager.HandleGroupMessage(func(ctx *MessageContext) error {
+		_, err := ctx.ReplyText("Hello World!")
+		return err
+	}, NewTextMatcher("hello", false))
+
+	// 注册私聊消息处理
+	manager.HandlePrivateMessage(func(ctx *MessageContext) error {
+		_, err := ctx.ReplyText("Hello! 这是私聊回复")
+		return err
+	}, NewTextMatcher("hello", false))
+
+	return nil
+}
+*/
+
+// RegisterCustomLogic 加载所有已注册的插件（向后兼容），新的逻辑请以插件形式注册
+func RegisterCustomLogic() {
+	if Manager == nil {
+		utils.Error("LogicManager 未初始化")
+		return
+	}
+
+	if err := Manager.GetPluginManager().Load(nil); err != nil {
+		utils.Errorf("部分插件加载失败: %v", err)
+	}
 
 	utils.Info("自定义逻辑注册完成")
 }
@@ -0,0 +1,81 @@
+package logic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 内置帮助命令，根据通过 RegisterCommand 注册的命令定义生成:
+//   /help           列出当前群可用的命令
+//   /help <命令>    显示命令的详细用法
+
+// RegisterHelpCommand 注册内置的帮助命令
+func (lm *LogicManager) RegisterHelpCommand() {
+	lm.AddCommand(NewCommand("help", lm.handleHelpCommand).
+		Describe("显示命令帮助").
+		OptionalArg("command", ArgString, "命令名称", nil))
+}
+
+// handleHelpCommand 处理 /help 命令
+func (lm *LogicManager) handleHelpCommand(ctx *MessageContext) error {
+	prefix := ctx.Config().CommandPrefix
+	commands := lm.availableCommands(ctx)
+
+	args := ctx.CommandArgs()
+	if args.Has("command") {
+		name := strings.TrimPrefix(args.String("command"), prefix)
+		for _, cmd := range commands {
+			if cmd.Name == name || containsString(cmd.Aliases, name) {
+				_, err := ctx.ReplyText(cmd.Help(prefix))
+				return err
+			}
+		}
+		return NewUsageError("未知的命令 %s", name)
+	}
+
+	if len(commands) == 0 {
+		_, err := ctx.ReplyText("没有可用的命令")
+		return err
+	}
+	var sb strings.Builder
+	sb.WriteString("可用命令:")
+	for _, cmd := range commands {
+		fmt.Fprintf(&sb, "\n%s%s", prefix, cmd.Name)
+		if cmd.Description != "" {
+			sb.WriteString(" - " + cmd.Description)
+		}
+	}
+	fmt.Fprintf(&sb, "\n发送 %shelp <命令> 查看详细用法", prefix)
+	_, err := ctx.ReplyText(sb.String())
+	return err
+}
+
+// availableCommands 获取在消息所在的群或私聊中可用的命令定义，按名称排序
+// 停用的路由与在本群被禁用的插件的命令不会列出
+func (lm *LogicManager) availableCommands(ctx *MessageContext) []*Command {
+	commands := make([]*Command, 0)
+	for _, route := range lm.router.GetRoutes() {
+		if route.Command == nil || !route.IsEnabled() {
+			continue
+		}
+		if route.Plugin != "" && !lm.plugins.EnabledFor(route.Plugin, ctx) {
+			continue
+		}
+		commands = append(commands, route.Command)
+	}
+	sort.SliceStable(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+	return commands
+}
+
+// containsString 检查切片中是否包含字符串
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,3 +1,4 @@
 package logic
 
-var GroupSystemPrompt = `你是一个群聊管理员中的忠实记录者，要求根据消息的信息调用工具记录`
+// GroupSystemPrompt 内置的群聊系统提示词，配置中未设置 groupPrompt 时使用
+const GroupSystemPrompt = `你是一个群聊管理员中的忠实记录者，要求根据消息的信息调用工具记录`
@@ -1,6 +1,14 @@
 package logic
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vintcessun/WE-Assistant/config"
 	"github.com/vintcessun/WE-Assistant/utils"
 	"github.com/LagrangeDev/LagrangeGo/client"
 	"github.com/LagrangeDev/LagrangeGo/client/event"
@@ -8,19 +16,113 @@ import (
 )
 
 // LogicManager 新的逻辑管理器
+// 多个账号共享同一个路由器，消息上下文中的 Client 指向接收该消息的账号
 type LogicManager struct {
-	client   *client.QQClient
-	router   *Router
-	eventBus *EventBus
+	clients    []*client.QQClient
+	router     *Router
+	eventBus   *EventBus
+	inflight   sync.WaitGroup
+	closeMu    sync.RWMutex
+	closed     bool
+	owners     map[uint32]bool
+	admins     map[uint32]bool
+	adminMu    sync.RWMutex
+	config     atomic.Pointer[config.Config]
+	plugins    *PluginManager
+	dispatcher *Dispatcher
+	sessions   *SessionManager
+	// ctx 所有消息上下文的父上下文，关闭超时时取消
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // NewLogicManager 创建新的逻辑管理器
-func NewLogicManager(client *client.QQClient) *LogicManager {
-	return &LogicManager{
-		client:   client,
+// 事件总线使用 GlobalEventBus，与 Publish* 系列方法发布事件的总线一致
+func NewLogicManager(clients ...*client.QQClient) *LogicManager {
+	lm := &LogicManager{
+		clients:  clients,
 		router:   NewRouter(),
-		eventBus: NewEventBus(),
+		eventBus: GlobalEventBus,
+	}
+	lm.plugins = newPluginManager(lm)
+	lm.router.SetPluginFilter(lm.plugins.EnabledFor)
+	lm.dispatcher = NewDispatcher(DefaultDispatcherConfig(), lm.handleMessage)
+	lm.sessions = NewSessionManager()
+	lm.sessions.claim = lm.claimQueued
+	lm.ctx, lm.cancel = context.WithCancel(context.Background())
+	lm.router.SetErrorHandler(lm.handleError)
+	return lm
+}
+
+// GetClients 获取所有账号的客户端
+func (lm *LogicManager) GetClients() []*client.QQClient {
+	return lm.clients
+}
+
+// GetClient 根据账号获取客户端
+func (lm *LogicManager) GetClient(account uint32) (*client.QQClient, bool) {
+	for _, c := range lm.clients {
+		if c.Uin == account {
+			return c, true
+		}
 	}
+	return nil, false
+}
+
+// SetAdmins 设置机器人主人与管理员
+func (lm *LogicManager) SetAdmins(owners []uint32, admins []uint32) {
+	ownerSet := make(map[uint32]bool, len(owners))
+	for _, uin := range owners {
+		ownerSet[uin] = true
+	}
+	adminSet := make(map[uint32]bool, len(admins))
+	for _, uin := range admins {
+		adminSet[uin] = true
+	}
+
+	lm.adminMu.Lock()
+	defer lm.adminMu.Unlock()
+	lm.owners = ownerSet
+	lm.admins = adminSet
+}
+
+// IsOwner 检查用户是否为机器人主人
+func (lm *LogicManager) IsOwner(uin uint32) bool {
+	lm.adminMu.RLock()
+	defer lm.adminMu.RUnlock()
+	return lm.owners[uin]
+}
+
+// IsAdmin 检查用户是否为管理员，主人同样视为管理员
+func (lm *LogicManager) IsAdmin(uin uint32) bool {
+	lm.adminMu.RLock()
+	defer lm.adminMu.RUnlock()
+	return lm.owners[uin] || lm.admins[uin]
+}
+
+// SetConfig 设置处理消息时使用的配置，之后收到的消息立即使用新配置
+func (lm *LogicManager) SetConfig(cfg *config.Config) {
+	lm.config.Store(cfg)
+}
+
+// GetConfig 获取处理消息时使用的配置，未设置时为 nil
+func (lm *LogicManager) GetConfig() *config.Config {
+	return lm.config.Load()
+}
+
+// SetDispatcherConfig 设置消息分发配置，需在 SetupEventListeners 之前调用
+func (lm *LogicManager) SetDispatcherConfig(cfg *DispatcherConfig) {
+	lm.dispatcher = NewDispatcher(cfg, lm.handleMessage)
+}
+
+// GetSessionManager 获取多轮会话管理器
+func (lm *LogicManager) GetSessionManager() *SessionManager {
+	return lm.sessions
+}
+
+// GetDispatcherStats 获取消息分发统计，包括队列深度与丢弃的消息数量
+func (lm *LogicManager) GetDispatcherStats() DispatcherStats {
+	return lm.dispatcher.Stats()
 }
 
 // GetRouter 获取路由器
@@ -33,79 +135,212 @@ func (lm *LogicManager) GetEventBus() *EventBus {
 	return lm.eventBus
 }
 
+// GetPluginManager 获取插件管理器
+func (lm *LogicManager) GetPluginManager() *PluginManager {
+	return lm.plugins
+}
+
+// Subscribe 订阅事件，插件初始化期间订阅的事件归属于该插件，插件未加载时不再处理
+func (lm *LogicManager) Subscribe(eventType string, handler EventHandler) {
+	if plugin := lm.plugins.initializing(); plugin != "" {
+		lm.plugins.recordEvent(plugin, eventType)
+		handler = lm.plugins.wrapEventHandler(plugin, handler)
+	}
+	lm.eventBus.Subscribe(eventType, handler)
+}
+
 // UseMiddleware 使用全局中间件
 func (lm *LogicManager) UseMiddleware(middleware Middleware) {
 	lm.router.Use(middleware)
 }
 
-// AddRoute 添加路由
-func (lm *LogicManager) AddRoute(route *Route) {
-	lm.router.AddRoute(route)
+// AddRoute 添加路由并返回路由ID，可用于之后移除、替换或停用该路由；添加失败时记录错误并返回空字符串
+func (lm *LogicManager) AddRoute(route *Route) string {
+	id, err := lm.Register(route)
+	if err != nil {
+		utils.Errorf("添加路由 %s 失败: %v", route.Name, err)
+	}
+	return id
+}
+
+// Register 添加路由并返回路由ID，插件初始化期间添加的路由归属于该插件
+func (lm *LogicManager) Register(route *Route) (string, error) {
+	if route.Plugin == "" {
+		route.Plugin = lm.plugins.initializing()
+	}
+	id, err := lm.router.Register(route)
+	if err != nil {
+		return "", err
+	}
+	lm.plugins.recordRoute(route)
+	return id, nil
+}
+
+// RemoveRoute 移除路由
+func (lm *LogicManager) RemoveRoute(id string) error {
+	route, ok := lm.router.GetRoute(id)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRouteNotFound, id)
+	}
+	if err := lm.router.RemoveRoute(id); err != nil {
+		return err
+	}
+	lm.plugins.forgetRoute(route)
+	return nil
+}
+
+// ReplaceRoute 使用新路由替换已有路由，新路由沿用原来的ID与所属插件
+func (lm *LogicManager) ReplaceRoute(id string, route *Route) error {
+	return lm.router.ReplaceRoute(id, route)
+}
+
+// SetRouteEnabled 启用或停用路由
+func (lm *LogicManager) SetRouteEnabled(id string, enabled bool) error {
+	return lm.router.SetRouteEnabled(id, enabled)
 }
 
 // HandlePrivateMessage 处理私聊消息的便捷方法
-func (lm *LogicManager) HandlePrivateMessage(handler HandlerFunc, matchers ...Matcher) {
+func (lm *LogicManager) HandlePrivateMessage(handler HandlerFunc, matchers ...Matcher) string {
 	route := NewRoute("private_message", NewHandlerAdapter(handler))
 	route.Match(NewMessageTypeMatcher("private"))
 	for _, matcher := range matchers {
 		route.Match(matcher)
 	}
-	lm.AddRoute(route)
+	return lm.AddRoute(route)
 }
 
 // HandleGroupMessage 处理群消息的便捷方法
-func (lm *LogicManager) HandleGroupMessage(handler HandlerFunc, matchers ...Matcher) {
+func (lm *LogicManager) HandleGroupMessage(handler HandlerFunc, matchers ...Matcher) string {
 	route := NewRoute("group_message", NewHandlerAdapter(handler))
 	route.Match(NewMessageTypeMatcher("group"))
 	for _, matcher := range matchers {
 		route.Match(matcher)
 	}
-	lm.AddRoute(route)
+	return lm.AddRoute(route)
 }
 
 // HandleFriendRequest 处理好友请求的便捷方法
-func (lm *LogicManager) HandleFriendRequest(handler HandlerFunc, matchers ...Matcher) {
+func (lm *LogicManager) HandleFriendRequest(handler HandlerFunc, matchers ...Matcher) string {
 	route := NewRoute("friend_request", NewHandlerAdapter(handler))
 	route.Match(NewMessageTypeMatcher("friend_request"))
 	for _, matcher := range matchers {
 		route.Match(matcher)
 	}
-	lm.AddRoute(route)
+	return lm.AddRoute(route)
+}
+
+// HandleFallback 兜底处理的便捷方法，仅在没有其他路由匹配时执行
+func (lm *LogicManager) HandleFallback(handler HandlerFunc, matchers ...Matcher) string {
+	route := NewRoute("fallback", NewHandlerAdapter(handler))
+	route.SetFallback(true)
+	for _, matcher := range matchers {
+		route.Match(matcher)
+	}
+	return lm.AddRoute(route)
 }
 
-// HandleCommand 处理命令的便捷方法
-func (lm *LogicManager) HandleCommand(prefix string, command string, handler HandlerFunc, middlewares ...Middleware) {
+// HandleCommand 处理命令的便捷方法，命令优先于普通路由执行，执行后不再执行其他路由
+func (lm *LogicManager) HandleCommand(prefix string, command string, handler HandlerFunc, middlewares ...Middleware) string {
 	route := NewRoute("command_"+command, NewHandlerAdapter(handler))
+	route.SetPriority(PriorityCommand).SetConsume(true)
 	route.Match(NewCommandMatcher(prefix, command))
 	for _, middleware := range middlewares {
 		route.Use(middleware)
 	}
-	lm.AddRoute(route)
+	return lm.AddRoute(route)
 }
 
-// SetupEventListeners 设置事件监听器
+// HandleScopedCommand 处理命令的便捷方法，命令前缀使用当前群与发送者配置的 commandPrefix
+func (lm *LogicManager) HandleScopedCommand(command string, handler HandlerFunc, middlewares ...Middleware) string {
+	route := NewRoute("command_"+command, NewHandlerAdapter(handler))
+	route.SetPriority(PriorityCommand).SetConsume(true)
+	route.Match(NewScopedCommandMatcher(command))
+	for _, middleware := range middlewares {
+		route.Use(middleware)
+	}
+	return lm.AddRoute(route)
+}
+
+// SetupEventListeners 启动消息分发并为所有账号设置事件监听器
 func (lm *LogicManager) SetupEventListeners() {
+	lm.dispatcher.Start()
+	for _, c := range lm.clients {
+		lm.setupClientEventListeners(c)
+	}
+}
+
+// setupClientEventListeners 为单个账号设置事件监听器
+func (lm *LogicManager) setupClientEventListeners(qqClient *client.QQClient) {
 	// 私聊消息事件
-	lm.client.PrivateMessageEvent.Subscribe(func(client *client.QQClient, event *message.PrivateMessage) {
+	qqClient.PrivateMessageEvent.Subscribe(func(client *client.QQClient, event *message.PrivateMessage) {
 		ctx := NewMessageContext(client, event)
 		lm.processMessage(ctx)
 	})
 
 	// 群消息事件
-	lm.client.GroupMessageEvent.Subscribe(func(client *client.QQClient, event *message.GroupMessage) {
+	qqClient.GroupMessageEvent.Subscribe(func(client *client.QQClient, event *message.GroupMessage) {
 		ctx := NewMessageContext(client, event)
 		lm.processMessage(ctx)
 	})
 
 	// 好友请求事件
-	lm.client.NewFriendRequestEvent.Subscribe(func(client *client.QQClient, event *event.NewFriendRequest) {
+	qqClient.NewFriendRequestEvent.Subscribe(func(client *client.QQClient, event *event.NewFriendRequest) {
 		ctx := NewMessageContext(client, event)
 		lm.processMessage(ctx)
 	})
 }
 
-// processMessage 处理消息
+// processMessage 将消息交给分发器，关闭后收到的消息与因队列已满被丢弃的消息不会处理
 func (lm *LogicManager) processMessage(ctx *MessageContext) {
+	if !lm.beginHandle() {
+		return
+	}
+	ctx.cfg = lm.GetConfig()
+	ctx.WithContext(lm.ctx)
+	ctx.sessions = lm.sessions
+
+	// 等待回复的会话直接接收消息，不经过分发与路由
+	if lm.sessions.Deliver(ctx) {
+		lm.inflight.Done()
+		PublishMessageReceived(ctx)
+		return
+	}
+
+	if err := lm.dispatcher.Submit(ctx); err != nil {
+		lm.inflight.Done()
+		utils.Warnf("丢弃来自会话 %s 的消息: %v", conversationKey(ctx), err)
+	}
+}
+
+// claimQueued 取出会话中已排队的消息交给等待回复的处理器，不再经过分发与路由
+func (lm *LogicManager) claimQueued(conv string, match func(*MessageContext) bool) *MessageContext {
+	ctx := lm.dispatcher.take(conv, match)
+	if ctx == nil {
+		return nil
+	}
+	lm.inflight.Done()
+	PublishMessageReceived(ctx)
+	return ctx
+}
+
+// handleError 记录路由返回的错误并发布错误事件，处理超时单独报告，多轮会话取消与等待超时不视为错误
+func (lm *LogicManager) handleError(err error, ctx *MessageContext) {
+	if isSessionEnd(err) {
+		utils.Debugf("会话结束: %v", err)
+		return
+	}
+	if errors.Is(err, ErrHandlerTimeout) {
+		utils.Warnf("处理消息超时: %v", err)
+	} else {
+		utils.Errorf("处理消息时发生错误: %v", err)
+	}
+	PublishError(err, ctx)
+}
+
+// handleMessage 在分发器的工作协程中处理消息
+func (lm *LogicManager) handleMessage(ctx *MessageContext) error {
+	defer lm.inflight.Done()
+
 	// 发布消息接收事件
 	PublishMessageReceived(ctx)
 	
@@ -114,11 +349,68 @@ func (lm *LogicManager) processMessage(ctx *MessageContext) {
 	
 	// 发布消息处理完成事件
 	PublishMessageProcessed(ctx)
+	return nil
+}
+
+// beginHandle 登记一个正在处理的消息，已关闭时返回 false
+func (lm *LogicManager) beginHandle() bool {
+	lm.closeMu.RLock()
+	defer lm.closeMu.RUnlock()
+	if lm.closed {
+		return false
+	}
+	lm.inflight.Add(1)
+	return true
 }
 
+// forcedShutdownTimeout 等待消息处理超时后，留给分发器与插件关闭的时间
+const forcedShutdownTimeout = 5 * time.Second
+
 // Close 关闭逻辑管理器
 func (lm *LogicManager) Close() {
-	lm.eventBus.Close()
+	lm.Shutdown(context.Background())
+}
+
+// Shutdown 停止接收新消息并结束等待回复的会话，等待正在处理的消息结束后关闭插件，再等待异步事件处理器结束后关闭事件总线
+// ctx 带有截止时间时，用去一半时间仍未处理完则取消所有消息的上下文，留出另一半时间让处理器退出；
+// ctx 结束时不再等待消息处理，另给分发器与插件 forcedShutdownTimeout 完成关闭后返回错误
+func (lm *LogicManager) Shutdown(ctx context.Context) error {
+	lm.closeMu.Lock()
+	lm.closed = true
+	lm.closeMu.Unlock()
+	// 等待回复的会话不会再收到消息，立即结束
+	lm.sessions.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		timer := time.AfterFunc(time.Until(deadline)/2, lm.cancel)
+		defer timer.Stop()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		lm.inflight.Wait()
+		close(done)
+	}()
+
+	var waitErr error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		waitErr = fmt.Errorf("等待消息处理结束超时: %w", ctx.Err())
+		// 截止时间已用完，仍给分发器与插件一段较短的时间完成关闭
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), forcedShutdownTimeout)
+		defer cancel()
+	}
+	lm.cancel()
+
+	dispatchErr := lm.dispatcher.Stop(ctx)
+	pluginErr := lm.plugins.Shutdown(ctx)
+	if waitErr != nil {
+		lm.eventBus.cancel()
+		return errors.Join(waitErr, dispatchErr, pluginErr)
+	}
+	return errors.Join(dispatchErr, pluginErr, lm.eventBus.Shutdown(ctx))
 }
 
 // 全局 LogicManager 实例
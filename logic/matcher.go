@@ -3,6 +3,7 @@ package logic
 import (
 	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/LagrangeDev/LagrangeGo/message"
 )
@@ -155,22 +156,29 @@ func NewGroupMatcher(groupIDs ...uint32) *GroupMatcher {
 type CommandMatcher struct {
 	Commands []string
 	Prefix   string
+	// ScopedPrefix 为 true 时使用当前群与发送者配置的命令前缀，忽略 Prefix
+	ScopedPrefix bool
 }
 
 func (m *CommandMatcher) Match(ctx *MessageContext) bool {
-	text := ctx.GetMessageText()
+	text := commandText(ctx.messageElements())
 	if text == "" {
 		return false
 	}
 	
+	prefix := m.Prefix
+	if m.ScopedPrefix {
+		prefix = ctx.Config().CommandPrefix
+	}
+
 	// 检查前缀
-	if m.Prefix != "" && !strings.HasPrefix(text, m.Prefix) {
+	if prefix != "" && !strings.HasPrefix(text, prefix) {
 		return false
 	}
 	
 	// 移除前缀
-	if m.Prefix != "" {
-		text = strings.TrimPrefix(text, m.Prefix)
+	if prefix != "" {
+		text = strings.TrimPrefix(text, prefix)
 	}
 	
 	// 分割命令和参数
@@ -196,11 +204,41 @@ func (m *CommandMatcher) Match(ctx *MessageContext) bool {
 	return false
 }
 
+// commandText 获取命令所在的文本，跳过开头的@、图片等元素与空白
+// 如 "@机器人 /remind 10m 喝水" 中的 "/remind 10m 喝水"
+func commandText(elements []message.IMessageElement) string {
+	for i, element := range elements {
+		if text, ok := element.(*message.TextElement); ok && strings.TrimSpace(text.Content) != "" {
+			return strings.TrimLeftFunc(extractTextFromElements(elements[i:]), unicode.IsSpace)
+		}
+	}
+	return ""
+}
+
 // NewCommandMatcher 创建命令匹配器
 func NewCommandMatcher(prefix string, commands ...string) *CommandMatcher {
 	return &CommandMatcher{Commands: commands, Prefix: prefix}
 }
 
+// NewScopedCommandMatcher 创建使用配置中命令前缀的命令匹配器
+func NewScopedCommandMatcher(commands ...string) *CommandMatcher {
+	return &CommandMatcher{Commands: commands, ScopedPrefix: true}
+}
+
+// FeatureMatcher 功能开关匹配器，功能在当前群与发送者处启用时匹配
+type FeatureMatcher struct {
+	Feature string
+}
+
+func (m *FeatureMatcher) Match(ctx *MessageContext) bool {
+	return ctx.FeatureEnabled(m.Feature)
+}
+
+// NewFeatureMatcher 创建功能开关匹配器
+func NewFeatureMatcher(feature string) *FeatureMatcher {
+	return &FeatureMatcher{Feature: feature}
+}
+
 // AndMatcher 逻辑AND匹配器
 type AndMatcher struct {
 	Matchers []Matcher
@@ -2,6 +2,7 @@ package logic
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/LagrangeDev/LagrangeGo/client/event"
@@ -48,10 +49,80 @@ func RecoveryMiddleware() Middleware {
 	}
 }
 
-// RateLimitMiddleware 限流中间件
-func RateLimitMiddleware(maxRequests int, window time.Duration) Middleware {
-	requests := make(map[string][]time.Time)
+// RateLimiter 可在运行时调整参数的限流器
+type RateLimiter struct {
+	maxRequests int
+	window      time.Duration
+	limitFunc   func(ctx *MessageContext) (int, time.Duration)
+	requests    map[string][]time.Time
+	mu          sync.Mutex
+}
+
+// NewRateLimiter 创建限流器，maxRequests <= 0 表示不限流
+func NewRateLimiter(maxRequests int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		maxRequests: maxRequests,
+		window:      window,
+		requests:    make(map[string][]time.Time),
+	}
+}
+
+// SetLimit 调整限流参数，立即生效
+func (rl *RateLimiter) SetLimit(maxRequests int, window time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.maxRequests = maxRequests
+	rl.window = window
+}
+
+// SetLimitFunc 按消息决定限流参数，如使用 ctx.Config() 中按群与用户覆盖的限流，设置后 SetLimit 的参数不再使用
+func (rl *RateLimiter) SetLimitFunc(fn func(ctx *MessageContext) (int, time.Duration)) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.limitFunc = fn
+}
+
+// Allow 记录一次请求并检查是否超出限制
+func (rl *RateLimiter) Allow(userID string) bool {
+	rl.mu.Lock()
+	maxRequests, window := rl.maxRequests, rl.window
+	rl.mu.Unlock()
+	return rl.allow(userID, maxRequests, window)
+}
+
+// allow 使用指定的限流参数记录一次请求并检查是否超出限制
+func (rl *RateLimiter) allow(userID string, maxRequests int, window time.Duration) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if maxRequests <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	
+	// 清理过期请求
+	userRequests := rl.requests[userID]
+	validRequests := make([]time.Time, 0)
+	for _, reqTime := range userRequests {
+		if now.Sub(reqTime) < window {
+			validRequests = append(validRequests, reqTime)
+		}
+	}
+	
+	// 检查限流
+	if len(validRequests) >= maxRequests {
+		rl.requests[userID] = validRequests
+		return false
+	}
 	
+	// 记录请求
+	rl.requests[userID] = append(validRequests, now)
+	return true
+}
+
+// Middleware 获取限流中间件
+func (rl *RateLimiter) Middleware() Middleware {
 	return func(next HandlerFunc) HandlerFunc {
 		return func(ctx *MessageContext) error {
 			var userID string
@@ -65,39 +136,70 @@ func RateLimitMiddleware(maxRequests int, window time.Duration) Middleware {
 				return next(ctx)
 			}
 			
-			now := time.Now()
-			
-			// 清理过期请求
-			userRequests := requests[userID]
-			validRequests := make([]time.Time, 0)
-			for _, reqTime := range userRequests {
-				if now.Sub(reqTime) < window {
-					validRequests = append(validRequests, reqTime)
-				}
+			rl.mu.Lock()
+			maxRequests, window, limitFunc := rl.maxRequests, rl.window, rl.limitFunc
+			rl.mu.Unlock()
+			if limitFunc != nil {
+				maxRequests, window = limitFunc(ctx)
 			}
 			
-			// 检查限流
-			if len(validRequests) >= maxRequests {
+			if !rl.allow(userID, maxRequests, window) {
 				logrus.Warnf("用户 %s 触发限流", userID)
 				return fmt.Errorf("请求过于频繁，请稍后再试")
 			}
 			
-			// 记录请求
-			validRequests = append(validRequests, now)
-			requests[userID] = validRequests
-			
 			return next(ctx)
 		}
 	}
 }
 
-// AuthMiddleware 认证中间件
-func AuthMiddleware(allowedUsers []uint32) Middleware {
+// RateLimitMiddleware 限流中间件
+func RateLimitMiddleware(maxRequests int, window time.Duration) Middleware {
+	return NewRateLimiter(maxRequests, window).Middleware()
+}
+
+// Authorizer 可在运行时调整白名单的认证器
+type Authorizer struct {
+	userSet map[uint32]bool
+	enabled bool
+	mu      sync.RWMutex
+}
+
+// NewAuthorizer 创建认证器，只有白名单中的用户可以通过
+func NewAuthorizer(allowedUsers []uint32) *Authorizer {
+	a := &Authorizer{enabled: true}
+	a.SetAllowedUsers(allowedUsers)
+	return a
+}
+
+// SetAllowedUsers 替换白名单，立即生效
+func (a *Authorizer) SetAllowedUsers(allowedUsers []uint32) {
 	userSet := make(map[uint32]bool)
 	for _, user := range allowedUsers {
 		userSet[user] = true
 	}
-	
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.userSet = userSet
+}
+
+// SetEnabled 启用或停用认证，停用时所有用户均可通过
+func (a *Authorizer) SetEnabled(enabled bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.enabled = enabled
+}
+
+// IsAllowed 检查用户是否可以通过
+func (a *Authorizer) IsAllowed(userID uint32) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return !a.enabled || a.userSet[userID]
+}
+
+// Middleware 获取认证中间件
+func (a *Authorizer) Middleware() Middleware {
 	return func(next HandlerFunc) HandlerFunc {
 		return func(ctx *MessageContext) error {
 			var userID uint32
@@ -112,7 +214,7 @@ func AuthMiddleware(allowedUsers []uint32) Middleware {
 			}
 			
 			// 检查权限
-			if !userSet[userID] {
+			if !a.IsAllowed(userID) {
 				logrus.Warnf("未授权用户 %d 尝试访问", userID)
 				return fmt.Errorf("无权限访问")
 			}
@@ -125,6 +227,11 @@ func AuthMiddleware(allowedUsers []uint32) Middleware {
 	}
 }
 
+// AuthMiddleware 认证中间件
+func AuthMiddleware(allowedUsers []uint32) Middleware {
+	return NewAuthorizer(allowedUsers).Middleware()
+}
+
 // GroupOnlyMiddleware 仅群聊中间件
 func GroupOnlyMiddleware() Middleware {
 	return func(next HandlerFunc) HandlerFunc {
@@ -0,0 +1,420 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/vintcessun/WE-Assistant/utils"
+)
+
+// Plugin 插件接口，每个插件负责一组相关的路由与事件订阅
+// 插件在 init 中调用 RegisterPlugin 注册自己，启动时按依赖顺序初始化
+type Plugin interface {
+	// Name 插件名称，全局唯一
+	Name() string
+	// Version 插件版本
+	Version() string
+	// Dependencies 依赖的插件名称，依赖会先于本插件初始化
+	Dependencies() []string
+	// Init 注册路由与事件订阅，期间通过 manager 添加的路由与 manager.Subscribe 订阅的事件都归属于该插件
+	Init(manager *LogicManager) error
+	// Shutdown 释放插件资源，在停止接收消息后调用
+	Shutdown() error
+}
+
+var (
+	// ErrPluginDisabled 插件在配置中被禁用
+	ErrPluginDisabled = errors.New("插件已禁用")
+	// ErrPluginNotFound 插件未注册
+	ErrPluginNotFound = errors.New("插件未注册")
+)
+
+var (
+	pluginRegistry   []Plugin
+	pluginRegistryMu sync.Mutex
+)
+
+// RegisterPlugin 注册插件，通常在插件所在文件的 init 中调用，名称重复时 panic
+func RegisterPlugin(plugin Plugin) {
+	pluginRegistryMu.Lock()
+	defer pluginRegistryMu.Unlock()
+
+	for _, p := range pluginRegistry {
+		if p.Name() == plugin.Name() {
+			panic(fmt.Sprintf("插件 %s 重复注册", plugin.Name()))
+		}
+	}
+	pluginRegistry = append(pluginRegistry, plugin)
+}
+
+// RegisteredPlugins 获取所有已注册的插件，按注册顺序排列
+func RegisteredPlugins() []Plugin {
+	pluginRegistryMu.Lock()
+	defer pluginRegistryMu.Unlock()
+	plugins := make([]Plugin, len(pluginRegistry))
+	copy(plugins, pluginRegistry)
+	return plugins
+}
+
+// PluginState 插件状态
+type PluginState string
+
+const (
+	PluginStateRegistered PluginState = "registered"
+	PluginStateLoaded     PluginState = "loaded"
+	PluginStateDisabled   PluginState = "disabled"
+	PluginStateFailed     PluginState = "failed"
+	PluginStateStopped    PluginState = "stopped"
+)
+
+// PluginInfo 插件信息，包括插件注册的路由与订阅的事件
+type PluginInfo struct {
+	Name         string
+	Version      string
+	Dependencies []string
+	State        PluginState
+	Error        string   // 加载失败的原因
+	Routes       []string // 路由ID
+	Events       []string // 订阅的事件类型
+}
+
+// pluginEntry 已注册插件的加载状态
+type pluginEntry struct {
+	plugin Plugin
+	info   PluginInfo
+}
+
+// PluginManager 插件管理器，负责按依赖顺序加载与关闭插件
+type PluginManager struct {
+	manager *LogicManager
+	entries map[string]*pluginEntry
+	order   []string // 注册顺序
+	loaded  []string // 加载顺序，关闭时按相反顺序
+	current string   // 正在初始化的插件
+	store   *PluginStateStore
+	mu      sync.RWMutex
+	logger  utils.Logger
+}
+
+// newPluginManager 创建插件管理器
+func newPluginManager(manager *LogicManager) *PluginManager {
+	return &PluginManager{
+		manager: manager,
+		entries: make(map[string]*pluginEntry),
+		logger:  utils.GetLogger().WithField("module", "plugin"),
+	}
+}
+
+// Load 按依赖顺序初始化所有已注册的插件，disabled 中的插件不会加载
+// 单个插件初始化失败或 panic 不影响其他插件，其注册的路由会被移除，依赖它的插件同样不会加载，所有失败合并后返回
+func (pm *PluginManager) Load(disabled []string) error {
+	disabledSet := make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		disabledSet[name] = true
+	}
+
+	pm.mu.Lock()
+	for _, plugin := range RegisteredPlugins() {
+		name := plugin.Name()
+		if _, ok := pm.entries[name]; ok {
+			continue
+		}
+		entry := &pluginEntry{
+			plugin: plugin,
+			info: PluginInfo{
+				Name:         name,
+				Version:      plugin.Version(),
+				Dependencies: plugin.Dependencies(),
+				State:        PluginStateRegistered,
+			},
+		}
+		if disabledSet[name] {
+			entry.info.State = PluginStateDisabled
+		}
+		delete(disabledSet, name)
+		pm.entries[name] = entry
+		pm.order = append(pm.order, name)
+	}
+	order := make([]string, len(pm.order))
+	copy(order, pm.order)
+	pm.mu.Unlock()
+
+	for name := range disabledSet {
+		if _, ok := pm.entry(name); !ok {
+			pm.logger.Warnf("配置中禁用的插件 %s 未注册", name)
+		}
+	}
+
+	var errs []error
+	visiting := make(map[string]bool)
+	var visit func(name string) error
+	visit = func(name string) error {
+		entry, ok := pm.entry(name)
+		if !ok {
+			return ErrPluginNotFound
+		}
+		switch entry.info.State {
+		case PluginStateLoaded:
+			return nil
+		case PluginStateDisabled:
+			return ErrPluginDisabled
+		case PluginStateFailed, PluginStateStopped:
+			return errors.New(entry.info.Error)
+		}
+		if visiting[name] {
+			return errors.New("存在循环依赖")
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+
+		for _, dep := range entry.info.Dependencies {
+			if err := visit(dep); err != nil {
+				err = fmt.Errorf("依赖 %s 不可用: %w", dep, err)
+				pm.fail(name, err)
+				errs = append(errs, fmt.Errorf("插件 %s 加载失败: %w", name, err))
+				return err
+			}
+		}
+
+		if err := pm.initPlugin(entry); err != nil {
+			pm.fail(name, err)
+			errs = append(errs, fmt.Errorf("插件 %s 加载失败: %w", name, err))
+			return err
+		}
+
+		pm.mu.Lock()
+		entry.info.State = PluginStateLoaded
+		pm.loaded = append(pm.loaded, name)
+		pm.mu.Unlock()
+		pm.logger.Infof("插件 %s (%s) 已加载", name, entry.info.Version)
+		return nil
+	}
+
+	for _, name := range order {
+		visit(name)
+	}
+	return errors.Join(errs...)
+}
+
+// initPlugin 初始化单个插件，panic 视为初始化失败
+func (pm *PluginManager) initPlugin(entry *pluginEntry) (err error) {
+	pm.mu.Lock()
+	pm.current = entry.info.Name
+	pm.mu.Unlock()
+	defer func() {
+		pm.mu.Lock()
+		pm.current = ""
+		pm.mu.Unlock()
+		if r := recover(); r != nil {
+			err = fmt.Errorf("初始化时发生panic: %v", r)
+		}
+	}()
+	return entry.plugin.Init(pm.manager)
+}
+
+// fail 记录插件加载失败，并移除其已注册的路由
+func (pm *PluginManager) fail(name string, err error) {
+	pm.manager.router.removeRoutes(func(route *Route) bool {
+		return route.Plugin == name
+	})
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	entry := pm.entries[name]
+	entry.info.State = PluginStateFailed
+	entry.info.Error = err.Error()
+	entry.info.Routes = nil
+	entry.info.Events = nil
+}
+
+// Shutdown 按加载的相反顺序关闭插件，单个插件关闭失败、panic 或超时不影响其他插件
+func (pm *PluginManager) Shutdown(ctx context.Context) error {
+	pm.mu.Lock()
+	loaded := pm.loaded
+	pm.loaded = nil
+	pm.mu.Unlock()
+
+	var errs []error
+	for i := len(loaded) - 1; i >= 0; i-- {
+		name := loaded[i]
+		entry, _ := pm.entry(name)
+		if err := pm.shutdownPlugin(ctx, entry.plugin); err != nil {
+			pm.logger.Errorf("插件 %s 关闭失败: %v", name, err)
+			errs = append(errs, fmt.Errorf("插件 %s 关闭失败: %w", name, err))
+		}
+
+		pm.mu.Lock()
+		entry.info.State = PluginStateStopped
+		pm.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// shutdownPlugin 关闭单个插件，超过截止时间后不再等待
+func (pm *PluginManager) shutdownPlugin(ctx context.Context, plugin Plugin) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		done <- plugin.Shutdown()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// entry 获取插件的加载状态
+func (pm *PluginManager) entry(name string) (*pluginEntry, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	entry, ok := pm.entries[name]
+	return entry, ok
+}
+
+// List 获取所有插件的信息，按注册顺序排列
+func (pm *PluginManager) List() []PluginInfo {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	infos := make([]PluginInfo, 0, len(pm.order))
+	for _, name := range pm.order {
+		infos = append(infos, pm.entries[name].info.clone())
+	}
+	return infos
+}
+
+// Get 获取插件信息
+func (pm *PluginManager) Get(name string) (PluginInfo, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	entry, ok := pm.entries[name]
+	if !ok {
+		return PluginInfo{}, false
+	}
+	return entry.info.clone(), true
+}
+
+// SetStateStore 设置保存每个群插件状态的存储
+func (pm *PluginManager) SetStateStore(store *PluginStateStore) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.store = store
+}
+
+// GetStateStore 获取保存每个群插件状态的存储，未设置时为 nil
+func (pm *PluginManager) GetStateStore() *PluginStateStore {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.store
+}
+
+// EnabledFor 检查插件是否可以处理该消息：插件已加载，且在消息所在的群中未被禁用
+func (pm *PluginManager) EnabledFor(plugin string, ctx *MessageContext) bool {
+	if !pm.IsLoaded(plugin) {
+		return false
+	}
+	groupUin := ctx.GroupUin()
+	store := pm.GetStateStore()
+	return groupUin == 0 || store == nil || store.IsEnabled(groupUin, plugin)
+}
+
+// IsLoaded 检查插件是否已加载
+func (pm *PluginManager) IsLoaded(name string) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	entry, ok := pm.entries[name]
+	return ok && entry.info.State == PluginStateLoaded
+}
+
+// clone 复制插件信息，避免调用方修改内部状态
+func (info PluginInfo) clone() PluginInfo {
+	info.Dependencies = append([]string(nil), info.Dependencies...)
+	info.Routes = append([]string(nil), info.Routes...)
+	info.Events = append([]string(nil), info.Events...)
+	return info
+}
+
+// initializing 获取正在初始化的插件名称
+func (pm *PluginManager) initializing() string {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.current
+}
+
+// recordRoute 记录插件注册的路由
+func (pm *PluginManager) recordRoute(route *Route) {
+	if route.Plugin == "" {
+		return
+	}
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if entry, ok := pm.entries[route.Plugin]; ok {
+		entry.info.Routes = append(entry.info.Routes, route.ID)
+	}
+}
+
+// forgetRoute 移除插件路由的记录
+func (pm *PluginManager) forgetRoute(route *Route) {
+	if route.Plugin == "" {
+		return
+	}
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	entry, ok := pm.entries[route.Plugin]
+	if !ok {
+		return
+	}
+	routes := make([]string, 0, len(entry.info.Routes))
+	for _, id := range entry.info.Routes {
+		if id != route.ID {
+			routes = append(routes, id)
+		}
+	}
+	entry.info.Routes = routes
+}
+
+// recordEvent 记录插件订阅的事件
+func (pm *PluginManager) recordEvent(plugin string, eventType string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if entry, ok := pm.entries[plugin]; ok {
+		entry.info.Events = append(entry.info.Events, eventType)
+	}
+}
+
+// wrapEventHandler 包装插件的事件处理器，插件未加载时忽略事件，panic 转换为错误
+func (pm *PluginManager) wrapEventHandler(plugin string, handler EventHandler) EventHandler {
+	return func(ctx context.Context, event Event) (err error) {
+		if !pm.IsLoaded(plugin) {
+			return nil
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("插件 %s 处理事件 %s 时发生panic: %v", plugin, event.GetType(), r)
+				pm.logger.Error(err)
+			}
+		}()
+		return handler(ctx, event)
+	}
+}
+
+// recoverPlugin 包装插件路由的处理链，panic 转换为错误交给路由器的错误处理器
+func recoverPlugin(plugin string, next HandlerFunc) HandlerFunc {
+	return func(ctx *MessageContext) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("插件 %s 处理消息时发生panic: %v", plugin, r)
+			}
+		}()
+		return next(ctx)
+	}
+}
@@ -0,0 +1,118 @@
+package logic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/LagrangeDev/LagrangeGo/client/entity"
+)
+
+// 内置插件管理命令，不属于任何插件，不能被禁用:
+//   /plugin list              列出插件及其在本群的状态
+//   /plugin enable <名称>     在本群启用插件，需要群主、群管理员或机器人管理员
+//   /plugin disable <名称>    在本群禁用插件，需要群主、群管理员或机器人管理员
+
+// RegisterPluginCommands 注册内置的插件管理命令，命令前缀使用配置中的 commandPrefix
+func (lm *LogicManager) RegisterPluginCommands() {
+	lm.AddCommand(NewCommand("plugin", lm.handlePluginCommand).
+		Describe("管理插件在本群的启用状态").
+		Use(GroupOnlyMiddleware()).
+		Arg("action", ArgString, "list、enable 或 disable").
+		OptionalArg("name", ArgString, "插件名称，enable 与 disable 时必填", nil).
+		Validate("action", OneOf("list", "enable", "disable")))
+}
+
+// handlePluginCommand 处理 /plugin 命令
+func (lm *LogicManager) handlePluginCommand(ctx *MessageContext) error {
+	reply, err := lm.runPluginCommand(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = ctx.ReplyText(reply)
+	return err
+}
+
+// runPluginCommand 执行 /plugin 命令，返回回复的内容
+func (lm *LogicManager) runPluginCommand(ctx *MessageContext) (string, error) {
+	args := ctx.CommandArgs()
+	action := args.String("action")
+	if action == "list" {
+		return lm.formatPluginList(ctx.GroupUin()), nil
+	}
+
+	if !args.Has("name") {
+		return "", NewUsageError("缺少参数 name")
+	}
+	if !lm.CanManageGroup(ctx) {
+		return "只有群主、群管理员或机器人管理员可以管理插件", nil
+	}
+	return lm.setGroupPlugin(ctx, args.String("name"), action == "enable")
+}
+
+// setGroupPlugin 在消息所在的群中启用或禁用插件，返回回复的内容
+func (lm *LogicManager) setGroupPlugin(ctx *MessageContext, name string, enabled bool) (string, error) {
+	info, ok := lm.plugins.Get(name)
+	if !ok {
+		return fmt.Sprintf("插件 %s 不存在", name), nil
+	}
+	if info.State != PluginStateLoaded {
+		return fmt.Sprintf("插件 %s 未加载 (%s)", name, info.State), nil
+	}
+
+	store := lm.plugins.GetStateStore()
+	if store == nil {
+		return "未启用按群管理插件", nil
+	}
+	if err := store.SetEnabled(ctx.GroupUin(), name, enabled); err != nil {
+		return "", err
+	}
+
+	action := "禁用"
+	if enabled {
+		action = "启用"
+	}
+	return fmt.Sprintf("已在本群%s插件 %s", action, name), nil
+}
+
+// formatPluginList 列出插件及其在群中的状态
+func (lm *LogicManager) formatPluginList(groupUin uint32) string {
+	infos := lm.plugins.List()
+	if len(infos) == 0 {
+		return "没有已注册的插件"
+	}
+
+	store := lm.plugins.GetStateStore()
+	var sb strings.Builder
+	sb.WriteString("插件列表:")
+	for _, info := range infos {
+		status := "未加载"
+		switch {
+		case info.State == PluginStateDisabled:
+			status = "配置中已禁用"
+		case info.State == PluginStateFailed:
+			status = "加载失败"
+		case info.State != PluginStateLoaded:
+		case store != nil && !store.IsEnabled(groupUin, info.Name):
+			status = "本群已禁用"
+		default:
+			status = "已启用"
+		}
+		fmt.Fprintf(&sb, "\n%s (%s) - %s", info.Name, info.Version, status)
+	}
+	return sb.String()
+}
+
+// CanManageGroup 检查发送者是否可以管理消息所在的群：机器人主人与管理员，或该群的群主与群管理员
+func (lm *LogicManager) CanManageGroup(ctx *MessageContext) bool {
+	senderUin := ctx.SenderUin()
+	if lm.IsAdmin(senderUin) {
+		return true
+	}
+
+	groupUin := ctx.GroupUin()
+	if groupUin == 0 || ctx.Client == nil {
+		return false
+	}
+	member := ctx.Client.GetCachedMemberInfo(senderUin, groupUin)
+	return member != nil && (member.Permission == entity.Owner || member.Permission == entity.Admin)
+}
@@ -0,0 +1,114 @@
+package logic
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// PluginStateStore 保存每个群单独启用或禁用的插件，未设置的插件在群中默认启用
+type PluginStateStore struct {
+	path   string
+	groups map[uint32]map[string]bool
+	mu     sync.RWMutex
+}
+
+// NewPluginStateStore 创建插件状态存储并加载已保存的状态，path 为空时不保存到文件
+func NewPluginStateStore(path string) (*PluginStateStore, error) {
+	s := &PluginStateStore{
+		path:   path,
+		groups: make(map[uint32]map[string]bool),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("读取插件状态文件失败: %w", err)
+	}
+
+	var saved map[string]map[string]bool
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("插件状态文件 %s 格式错误: %w", path, err)
+	}
+	for group, plugins := range saved {
+		groupUin, err := strconv.ParseUint(group, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("插件状态文件 %s 中的群号 %q 无效", path, group)
+		}
+		s.groups[uint32(groupUin)] = plugins
+	}
+	return s, nil
+}
+
+// IsEnabled 检查插件在群中是否启用
+func (s *PluginStateStore) IsEnabled(groupUin uint32, plugin string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	enabled, ok := s.groups[groupUin][plugin]
+	return !ok || enabled
+}
+
+// SetEnabled 在群中启用或禁用插件并保存
+func (s *PluginStateStore) SetEnabled(groupUin uint32, plugin string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plugins := s.groups[groupUin]
+	if plugins == nil {
+		plugins = make(map[string]bool)
+		s.groups[groupUin] = plugins
+	}
+	previous, existed := plugins[plugin]
+	plugins[plugin] = enabled
+
+	if err := s.save(); err != nil {
+		// 保存失败时恢复，保证内存中的状态与文件一致
+		if existed {
+			plugins[plugin] = previous
+		} else {
+			delete(plugins, plugin)
+		}
+		return err
+	}
+	return nil
+}
+
+// save 写入插件状态文件，先写临时文件再替换，调用方需持有锁
+func (s *PluginStateStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	saved := make(map[string]map[string]bool, len(s.groups))
+	for groupUin, plugins := range s.groups {
+		if len(plugins) > 0 {
+			saved[strconv.FormatUint(uint64(groupUin), 10)] = plugins
+		}
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化插件状态失败: %w", err)
+	}
+
+	if dir := filepath.Dir(s.path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("创建插件状态目录失败: %w", err)
+		}
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("写入插件状态文件失败: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("写入插件状态文件失败: %w", err)
+	}
+	return nil
+}
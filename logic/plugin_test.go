@@ -0,0 +1,297 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/LagrangeDev/LagrangeGo/message"
+	"github.com/vintcessun/WE-Assistant/config"
+)
+
+// testPlugin 记录初始化与关闭顺序的插件
+type testPlugin struct {
+	name     string
+	deps     []string
+	init     func(manager *LogicManager) error
+	shutdown func() error
+	events   *[]string
+	mu       *sync.Mutex
+}
+
+func (p *testPlugin) Name() string           { return p.name }
+func (p *testPlugin) Version() string        { return "1.0.0" }
+func (p *testPlugin) Dependencies() []string { return p.deps }
+
+func (p *testPlugin) Init(manager *LogicManager) error {
+	p.record("init " + p.name)
+	if p.init != nil {
+		return p.init(manager)
+	}
+	return nil
+}
+
+func (p *testPlugin) Shutdown() error {
+	p.record("shutdown " + p.name)
+	if p.shutdown != nil {
+		return p.shutdown()
+	}
+	return nil
+}
+
+func (p *testPlugin) record(event string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	*p.events = append(*p.events, event)
+}
+
+// pluginRecorder 创建共享同一份记录的测试插件
+type pluginRecorder struct {
+	events []string
+	mu     sync.Mutex
+}
+
+func (r *pluginRecorder) plugin(name string, deps ...string) *testPlugin {
+	return &testPlugin{name: name, deps: deps, events: &r.events, mu: &r.mu}
+}
+
+func (r *pluginRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+// usePlugins 在测试期间替换全局的插件注册表
+func usePlugins(t *testing.T, plugins ...Plugin) {
+	t.Helper()
+	pluginRegistryMu.Lock()
+	saved := pluginRegistry
+	pluginRegistry = nil
+	pluginRegistryMu.Unlock()
+	t.Cleanup(func() {
+		pluginRegistryMu.Lock()
+		pluginRegistry = saved
+		pluginRegistryMu.Unlock()
+	})
+
+	for _, plugin := range plugins {
+		RegisterPlugin(plugin)
+	}
+}
+
+// newPluginTestManager 创建使用默认配置的逻辑管理器
+func newPluginTestManager() *LogicManager {
+	lm := NewLogicManager()
+	lm.SetConfig(config.Default())
+	return lm
+}
+
+func pluginState(t *testing.T, lm *LogicManager, name string) PluginInfo {
+	t.Helper()
+	info, ok := lm.GetPluginManager().Get(name)
+	if !ok {
+		t.Fatalf("plugin %s not found", name)
+	}
+	return info
+}
+
+func TestPluginLoadOrder(t *testing.T) {
+	r := &pluginRecorder{}
+	usePlugins(t, r.plugin("c", "b"), r.plugin("b", "a"), r.plugin("a"), r.plugin("d", "a"))
+
+	lm := newPluginTestManager()
+	if err := lm.GetPluginManager().Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := lm.GetPluginManager().Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"init a", "init b", "init c", "init d",
+		"shutdown d", "shutdown c", "shutdown b", "shutdown a",
+	}
+	if got := r.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, info := range lm.GetPluginManager().List() {
+		if info.State != PluginStateStopped {
+			t.Errorf("plugin %s state = %s, want stopped", info.Name, info.State)
+		}
+	}
+}
+
+func TestPluginMissingDependency(t *testing.T) {
+	r := &pluginRecorder{}
+	usePlugins(t, r.plugin("a", "missing"), r.plugin("b", "a"), r.plugin("c"))
+
+	lm := newPluginTestManager()
+	err := lm.GetPluginManager().Load(nil)
+	if !errors.Is(err, ErrPluginNotFound) {
+		t.Fatalf("expected ErrPluginNotFound, got %v", err)
+	}
+
+	for _, name := range []string{"a", "b"} {
+		if info := pluginState(t, lm, name); info.State != PluginStateFailed || info.Error == "" {
+			t.Errorf("plugin %s: got %+v, want failed", name, info)
+		}
+	}
+	if info := pluginState(t, lm, "c"); info.State != PluginStateLoaded {
+		t.Errorf("plugin c: got %s, want loaded", info.State)
+	}
+	if got := r.get(); !reflect.DeepEqual(got, []string{"init c"}) {
+		t.Errorf("got %v, want only c initialized", got)
+	}
+}
+
+func TestPluginDisabled(t *testing.T) {
+	r := &pluginRecorder{}
+	usePlugins(t, r.plugin("a"), r.plugin("b", "a"))
+
+	lm := newPluginTestManager()
+	err := lm.GetPluginManager().Load([]string{"a"})
+	if !errors.Is(err, ErrPluginDisabled) {
+		t.Fatalf("expected ErrPluginDisabled, got %v", err)
+	}
+	if info := pluginState(t, lm, "a"); info.State != PluginStateDisabled {
+		t.Errorf("plugin a: got %s, want disabled", info.State)
+	}
+	if info := pluginState(t, lm, "b"); info.State != PluginStateFailed {
+		t.Errorf("plugin b: got %s, want failed", info.State)
+	}
+	if got := r.get(); len(got) != 0 {
+		t.Errorf("no plugin should be initialized, got %v", got)
+	}
+}
+
+func TestPluginDependencyCycle(t *testing.T) {
+	r := &pluginRecorder{}
+	usePlugins(t, r.plugin("a", "b"), r.plugin("b", "a"), r.plugin("c"))
+
+	lm := newPluginTestManager()
+	err := lm.GetPluginManager().Load(nil)
+	if err == nil || !strings.Contains(err.Error(), "循环依赖") {
+		t.Fatalf("expected a cycle error, got %v", err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if info := pluginState(t, lm, name); info.State != PluginStateFailed {
+			t.Errorf("plugin %s: got %s, want failed", name, info.State)
+		}
+	}
+	if !lm.GetPluginManager().IsLoaded("c") {
+		t.Error("plugin c should be loaded")
+	}
+}
+
+func TestPluginInitPanicRemovesRoutes(t *testing.T) {
+	r := &pluginRecorder{}
+	bad := r.plugin("bad")
+	bad.init = func(manager *LogicManager) error {
+		manager.HandleGroupMessage(func(ctx *MessageContext) error { return nil })
+		panic("boom")
+	}
+	good := r.plugin("good")
+	good.init = func(manager *LogicManager) error {
+		manager.HandleGroupMessage(func(ctx *MessageContext) error { return nil })
+		return nil
+	}
+	usePlugins(t, bad, good)
+
+	lm := newPluginTestManager()
+	err := lm.GetPluginManager().Load(nil)
+	if err == nil || !strings.Contains(err.Error(), "panic") {
+		t.Fatalf("expected a panic error, got %v", err)
+	}
+
+	info := pluginState(t, lm, "bad")
+	if info.State != PluginStateFailed || len(info.Routes) != 0 {
+		t.Errorf("plugin bad: got %+v, want failed without routes", info)
+	}
+	if info := pluginState(t, lm, "good"); info.State != PluginStateLoaded || len(info.Routes) != 1 {
+		t.Errorf("plugin good: got %+v, want loaded with one route", info)
+	}
+	for _, route := range lm.router.GetRoutes() {
+		if route.Plugin == "bad" {
+			t.Errorf("route %s of the failed plugin was not removed", route.ID)
+		}
+	}
+}
+
+func TestPluginHandlerPanic(t *testing.T) {
+	r := &pluginRecorder{}
+	ran := false
+	bad := r.plugin("bad")
+	bad.init = func(manager *LogicManager) error {
+		manager.AddRoute(NewRoute("bad", NewHandlerAdapter(func(ctx *MessageContext) error {
+			panic("boom")
+		})).SetPriority(10))
+		return nil
+	}
+	good := r.plugin("good")
+	good.init = func(manager *LogicManager) error {
+		manager.AddRoute(NewRoute("good", NewHandlerAdapter(func(ctx *MessageContext) error {
+			ran = true
+			return nil
+		})))
+		return nil
+	}
+	usePlugins(t, bad, good)
+
+	lm := newPluginTestManager()
+	if err := lm.GetPluginManager().Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	var handled []error
+	lm.router.SetErrorHandler(func(err error, ctx *MessageContext) {
+		handled = append(handled, err)
+	})
+
+	ctx := NewMessageContext(nil, &message.GroupMessage{GroupUin: 1, Sender: &message.Sender{Uin: 2}})
+	ctx.cfg = lm.GetConfig()
+	lm.router.Handle(ctx)
+
+	if len(handled) != 1 || !strings.Contains(handled[0].Error(), "插件 bad") {
+		t.Errorf("expected the panic as an error, got %v", handled)
+	}
+	if !ran {
+		t.Error("route of another plugin should still run")
+	}
+}
+
+func TestPluginShutdownErrors(t *testing.T) {
+	r := &pluginRecorder{}
+	a := r.plugin("a")
+	b := r.plugin("b")
+	b.shutdown = func() error { panic("boom") }
+	c := r.plugin("c")
+	c.shutdown = func() error { return errors.New("failed") }
+	usePlugins(t, a, b, c)
+
+	lm := newPluginTestManager()
+	if err := lm.GetPluginManager().Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	err := lm.GetPluginManager().Shutdown(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "插件 b") || !strings.Contains(err.Error(), "插件 c") {
+		t.Fatalf("expected errors from b and c, got %v", err)
+	}
+	got := r.get()
+	if got[len(got)-1] != "shutdown a" {
+		t.Errorf("plugin a should still be shut down, got %v", got)
+	}
+}
+
+func TestRegisterPluginDuplicate(t *testing.T) {
+	r := &pluginRecorder{}
+	usePlugins(t, r.plugin("a"))
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate plugin should panic")
+		}
+	}()
+	RegisterPlugin(r.plugin("a"))
+}
@@ -3,6 +3,7 @@ package logic
 import (
 	"image/png"
 	"os"
+	"sync"
 
 	"github.com/LagrangeDev/LagrangeGo/client"
 	"github.com/LagrangeDev/LagrangeGo/message"
@@ -17,12 +18,16 @@ func HandleGroupMessage(ctx *MessageContext) error {
 	return nil
 }
 
-var messages = make([]*MessageContext, 0)
+var (
+	messages   = make([]*MessageContext, 0)
+	messagesMu sync.Mutex // 不同会话的消息并行处理
+)
 
 func HandlePrivateMessage(ctx *MessageContext) error {
 	if privateMsg, ok := ctx.GetPrivateMessage(); ok {
 		formattedMsg := utils.FormatPrivateMessageJSON(privateMsg)
 		utils.Infof("收到私聊消息: %s", formattedMsg)
+		messagesMu.Lock()
 		messages = append(messages, ctx)
 		privateMessages := make([]*message.PrivateMessage, 0, len(messages))
 		for _, m := range messages {
@@ -30,6 +35,7 @@ func HandlePrivateMessage(ctx *MessageContext) error {
 				privateMessages = append(privateMessages, pm)
 			}
 		}
+		messagesMu.Unlock()
 		if img, err := utils.GenerateChatImage(privateMessages, 1024); err != nil {
 			file, err := os.Create("output.png")
 			if err != nil {
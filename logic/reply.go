@@ -0,0 +1,186 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/LagrangeDev/LagrangeGo/client"
+	"github.com/LagrangeDev/LagrangeGo/message"
+)
+
+var (
+	// ErrNoReplyTarget 消息不是群聊或私聊消息，无法回复
+	ErrNoReplyTarget = errors.New("没有可回复的会话")
+	// ErrSendFailed 服务器没有返回消息序号
+	ErrSendFailed = errors.New("消息发送失败")
+)
+
+// SentMessage 已发送的消息，保存撤回所需的信息
+type SentMessage struct {
+	ID         uint32 // 消息序号
+	GroupUin   uint32 // 群号，私聊消息为 0
+	TargetUin  uint32 // 私聊对象，群消息为 0
+	InternalID uint32
+	ClientSeq  uint32
+	Time       uint32
+	client     *client.QQClient
+}
+
+// IsGroup 检查是否为群消息
+func (m *SentMessage) IsGroup() bool {
+	return m.GroupUin != 0
+}
+
+// Recall 撤回消息
+func (m *SentMessage) Recall() error {
+	if m.IsGroup() {
+		return m.client.RecallGroupMessage(m.GroupUin, m.ID)
+	}
+	return m.client.RecallFriendMessage(m.TargetUin, m.ID, m.InternalID, m.ClientSeq, m.Time)
+}
+
+// Reply 向消息所在的群或私聊发送消息
+func (mc *MessageContext) Reply(elements ...message.IMessageElement) (*SentMessage, error) {
+	if mc.Client == nil {
+		return nil, ErrNoReplyTarget
+	}
+
+	if groupMsg, ok := mc.GetGroupMessage(); ok {
+		sent, err := mc.Client.SendGroupMessage(groupMsg.GroupUin, elements)
+		if err != nil {
+			return nil, fmt.Errorf("发送群消息失败: %w", err)
+		}
+		if sent == nil {
+			return nil, fmt.Errorf("发送群消息失败: %w", ErrSendFailed)
+		}
+		return &SentMessage{
+			ID:         sent.ID,
+			GroupUin:   sent.GroupUin,
+			InternalID: sent.InternalID,
+			Time:       sent.Time,
+			client:     mc.Client,
+		}, nil
+	}
+
+	if privateMsg, ok := mc.GetPrivateMessage(); ok {
+		sent, err := mc.Client.SendPrivateMessage(privateMsg.Sender.Uin, elements)
+		if err != nil {
+			return nil, fmt.Errorf("发送私聊消息失败: %w", err)
+		}
+		if sent == nil {
+			return nil, fmt.Errorf("发送私聊消息失败: %w", ErrSendFailed)
+		}
+		return &SentMessage{
+			ID:         sent.ID,
+			TargetUin:  sent.Target,
+			InternalID: sent.InternalID,
+			ClientSeq:  sent.ClientSeq,
+			Time:       sent.Time,
+			client:     mc.Client,
+		}, nil
+	}
+
+	return nil, ErrNoReplyTarget
+}
+
+// ReplyText 回复文本
+func (mc *MessageContext) ReplyText(text string) (*SentMessage, error) {
+	return mc.Reply(message.NewText(text))
+}
+
+// ReplyQuote 引用收到的消息进行回复
+func (mc *MessageContext) ReplyQuote(elements ...message.IMessageElement) (*SentMessage, error) {
+	return mc.Compose().Quote().Append(elements...).Send()
+}
+
+// ReplyAt 在群聊中@发送者进行回复，私聊中直接回复
+func (mc *MessageContext) ReplyAt(elements ...message.IMessageElement) (*SentMessage, error) {
+	return mc.Compose().AtSender().Append(elements...).Send()
+}
+
+// Compose 创建回复该消息的消息构建器
+func (mc *MessageContext) Compose() *MessageBuilder {
+	b := NewMessageBuilder()
+	b.ctx = mc
+	return b
+}
+
+// MessageBuilder 消息构建器，依次组合文本、@、图片、表情等元素
+type MessageBuilder struct {
+	ctx      *MessageContext
+	quote    message.IMessageElement
+	elements []message.IMessageElement
+}
+
+// NewMessageBuilder 创建消息构建器，通过 Elements 获取组合好的元素
+func NewMessageBuilder() *MessageBuilder {
+	return &MessageBuilder{elements: make([]message.IMessageElement, 0)}
+}
+
+// Text 添加文本
+func (b *MessageBuilder) Text(text string) *MessageBuilder {
+	return b.Append(message.NewText(text))
+}
+
+// Textf 添加格式化文本
+func (b *MessageBuilder) Textf(format string, args ...interface{}) *MessageBuilder {
+	return b.Text(fmt.Sprintf(format, args...))
+}
+
+// At 添加@，群聊之外的消息中无效
+func (b *MessageBuilder) At(uin uint32, display ...string) *MessageBuilder {
+	return b.Append(message.NewAt(uin, display...))
+}
+
+// AtSender 在群聊中@消息的发送者，私聊或没有关联消息时忽略
+func (b *MessageBuilder) AtSender() *MessageBuilder {
+	if b.ctx == nil || b.ctx.GroupUin() == 0 {
+		return b
+	}
+	return b.At(b.ctx.SenderUin()).Text(" ")
+}
+
+// Image 添加图片
+func (b *MessageBuilder) Image(data []byte) *MessageBuilder {
+	return b.Append(message.NewImage(data))
+}
+
+// Face 添加QQ表情
+func (b *MessageBuilder) Face(id uint32) *MessageBuilder {
+	return b.Append(message.NewFace(id))
+}
+
+// Quote 引用关联的消息，引用总是位于消息开头，没有关联消息时忽略
+func (b *MessageBuilder) Quote() *MessageBuilder {
+	if b.ctx == nil {
+		return b
+	}
+	if groupMsg, ok := b.ctx.GetGroupMessage(); ok {
+		b.quote = message.NewGroupReply(groupMsg)
+	} else if privateMsg, ok := b.ctx.GetPrivateMessage(); ok {
+		b.quote = message.NewPrivateReply(privateMsg)
+	}
+	return b
+}
+
+// Append 添加任意消息元素
+func (b *MessageBuilder) Append(elements ...message.IMessageElement) *MessageBuilder {
+	b.elements = append(b.elements, elements...)
+	return b
+}
+
+// Elements 获取组合好的消息元素
+func (b *MessageBuilder) Elements() []message.IMessageElement {
+	if b.quote == nil {
+		return b.elements
+	}
+	return append([]message.IMessageElement{b.quote}, b.elements...)
+}
+
+// Send 回复关联的消息，通过 NewMessageBuilder 创建时返回 ErrNoReplyTarget
+func (b *MessageBuilder) Send() (*SentMessage, error) {
+	if b.ctx == nil {
+		return nil, ErrNoReplyTarget
+	}
+	return b.ctx.Reply(b.Elements()...)
+}
@@ -0,0 +1,92 @@
+package logic
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/LagrangeDev/LagrangeGo/message"
+)
+
+func newPrivateTestContext() *MessageContext {
+	return NewMessageContext(nil, &message.PrivateMessage{
+		ID:       7,
+		Sender:   &message.Sender{Uin: 2},
+		Elements: []message.IMessageElement{message.NewText("hi")},
+	})
+}
+
+func TestMessageBuilderElements(t *testing.T) {
+	elements := NewMessageBuilder().
+		Text("a").
+		Textf("%d", 1).
+		At(10001, "@someone").
+		Face(14).
+		Append(message.NewText("b")).
+		Elements()
+
+	want := []message.IMessageElement{
+		message.NewText("a"),
+		message.NewText("1"),
+		message.NewAt(10001, "@someone"),
+		message.NewFace(14),
+		message.NewText("b"),
+	}
+	if !reflect.DeepEqual(elements, want) {
+		t.Errorf("elements = %#v, want %#v", elements, want)
+	}
+
+	if image, ok := NewMessageBuilder().Image([]byte("png")).Elements()[0].(*message.ImageElement); !ok || image.Stream == nil {
+		t.Errorf("Image should add an image element with the data")
+	}
+}
+
+func TestMessageBuilderGroup(t *testing.T) {
+	ctx := newRouterTestContext()
+	elements := ctx.Compose().Text("hello").Quote().AtSender().Elements()
+
+	if len(elements) != 4 {
+		t.Fatalf("elements = %#v", elements)
+	}
+	reply, ok := elements[0].(*message.ReplyElement)
+	if !ok || reply.SenderUin != 2 {
+		t.Errorf("quote should come first and refer to the sender, got %#v", elements[0])
+	}
+	if at, ok := elements[2].(*message.AtElement); !ok || at.TargetUin != 2 {
+		t.Errorf("AtSender should mention the sender, got %#v", elements[2])
+	}
+}
+
+func TestMessageBuilderPrivate(t *testing.T) {
+	ctx := newPrivateTestContext()
+	elements := ctx.Compose().AtSender().Quote().Text("hello").Elements()
+
+	if len(elements) != 2 {
+		t.Fatalf("AtSender should be ignored in private chat, got %#v", elements)
+	}
+	if reply, ok := elements[0].(*message.ReplyElement); !ok || reply.ReplySeq != 7 {
+		t.Errorf("quote = %#v", elements[0])
+	}
+}
+
+func TestMessageBuilderWithoutContext(t *testing.T) {
+	b := NewMessageBuilder().Quote().AtSender().Text("hello")
+	if elements := b.Elements(); len(elements) != 1 {
+		t.Errorf("Quote and AtSender need a message, got %#v", elements)
+	}
+	if _, err := b.Send(); !errors.Is(err, ErrNoReplyTarget) {
+		t.Errorf("expected ErrNoReplyTarget, got %v", err)
+	}
+}
+
+func TestReplyWithoutClient(t *testing.T) {
+	if _, err := newRouterTestContext().ReplyText("hello"); !errors.Is(err, ErrNoReplyTarget) {
+		t.Errorf("expected ErrNoReplyTarget, got %v", err)
+	}
+}
+
+func TestSentMessageIsGroup(t *testing.T) {
+	if !(&SentMessage{GroupUin: 1}).IsGroup() || (&SentMessage{TargetUin: 2}).IsGroup() {
+		t.Error("IsGroup should depend on GroupUin")
+	}
+}
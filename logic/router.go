@@ -2,13 +2,18 @@ package logic
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/LagrangeDev/LagrangeGo/client"
 	"github.com/LagrangeDev/LagrangeGo/client/event"
 	"github.com/LagrangeDev/LagrangeGo/message"
 	"github.com/sirupsen/logrus"
+	"github.com/vintcessun/WE-Assistant/config"
 )
 
 // MessageContext 消息上下文
@@ -17,6 +22,11 @@ type MessageContext struct {
 	Message  interface{}
 	Metadata map[string]interface{}
 	ctx      context.Context
+	cfg      *config.Config
+	scope    *config.Scope
+	stopped  bool
+	sessions *SessionManager
+	detach   func() // 让出所属会话，由消息分发器设置
 }
 
 // NewMessageContext 创建新的消息上下文
@@ -29,6 +39,11 @@ func NewMessageContext(client *client.QQClient, msg interface{}) *MessageContext
 	}
 }
 
+// Account 获取接收该消息的账号
+func (mc *MessageContext) Account() uint32 {
+	return mc.Client.Uin
+}
+
 // GetContext 获取上下文
 func (mc *MessageContext) GetContext() context.Context {
 	return mc.ctx
@@ -85,15 +100,81 @@ func (mc *MessageContext) GetFriendRequest() (*event.NewFriendRequest, bool) {
 	return nil, false
 }
 
+// StopPropagation 标记消息已被处理，之后的路由与兜底路由都不再执行
+func (mc *MessageContext) StopPropagation() {
+	mc.stopped = true
+}
+
+// IsPropagationStopped 检查消息是否已被标记为处理完毕
+func (mc *MessageContext) IsPropagationStopped() bool {
+	return mc.stopped
+}
+
+// GroupUin 获取消息所在的群号，非群消息时返回 0
+func (mc *MessageContext) GroupUin() uint32 {
+	if groupMsg, ok := mc.GetGroupMessage(); ok {
+		return groupMsg.GroupUin
+	}
+	return 0
+}
+
+// SenderUin 获取消息发送者或好友请求来源的QQ号
+func (mc *MessageContext) SenderUin() uint32 {
+	if privateMsg, ok := mc.GetPrivateMessage(); ok {
+		return privateMsg.Sender.Uin
+	}
+	if groupMsg, ok := mc.GetGroupMessage(); ok {
+		return groupMsg.Sender.Uin
+	}
+	if friendReq, ok := mc.GetFriendRequest(); ok {
+		return friendReq.SourceUin
+	}
+	return 0
+}
+
+// Config 获取当前群与发送者最终生效的配置，[groups."群号"] 与 [users."QQ号"] 覆盖默认配置
+// 同一条消息处理期间配置保持不变，配置重新加载后的消息使用新配置
+func (mc *MessageContext) Config() *config.Scope {
+	if mc.scope == nil {
+		cfg := mc.cfg
+		if cfg == nil {
+			cfg = config.GlobalConfig
+		}
+		if cfg == nil {
+			cfg = config.Default()
+		}
+		mc.scope = cfg.Resolve(mc.GroupUin(), mc.SenderUin())
+	}
+	return mc.scope
+}
+
+// FeatureEnabled 检查功能在当前群与发送者处是否启用
+func (mc *MessageContext) FeatureEnabled(feature string) bool {
+	return mc.Config().Enabled(feature)
+}
+
+// SystemPrompt 获取当前群与发送者使用的大模型系统提示词
+func (mc *MessageContext) SystemPrompt() string {
+	if prompt := mc.Config().Prompt; prompt != "" {
+		return prompt
+	}
+	return GroupSystemPrompt
+}
+
 // GetMessageText 获取消息文本内容
 func (mc *MessageContext) GetMessageText() string {
+	return extractTextFromElements(mc.messageElements())
+}
+
+// messageElements 获取私聊或群聊消息的元素
+func (mc *MessageContext) messageElements() []message.IMessageElement {
 	if privateMsg, ok := mc.GetPrivateMessage(); ok {
-		return extractTextFromElements(privateMsg.Elements)
+		return privateMsg.Elements
 	}
 	if groupMsg, ok := mc.GetGroupMessage(); ok {
-		return extractTextFromElements(groupMsg.Elements)
+		return groupMsg.Elements
 	}
-	return ""
+	return nil
 }
 
 // extractTextFromElements 从消息元素中提取文本
@@ -133,13 +214,70 @@ func (ha *HandlerAdapter) Handle(ctx *MessageContext) error {
 	return ha.handler(ctx)
 }
 
+// 路由优先级，数值越大越先执行，相同优先级按添加顺序执行
+const (
+	PriorityLow     = -100
+	PriorityDefault = 0
+	PriorityCommand = 100
+	PriorityHigh    = 200
+)
+
+var (
+	// ErrRouteNotFound 路由不存在
+	ErrRouteNotFound = errors.New("路由不存在")
+	// ErrRouteExists 路由ID已存在
+	ErrRouteExists = errors.New("路由ID已存在")
+	// ErrHandlerTimeout 路由处理超时
+	ErrHandlerTimeout = errors.New("处理超时")
+)
+
+// TimeoutError 路由处理超过了超时时间
+// 处理器需要通过 ctx.GetContext() 感知超时并尽快返回，返回后才会报告超时
+type TimeoutError struct {
+	RouteID string
+	Timeout time.Duration
+	Err     error // 处理器返回的错误，可能为 nil
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("路由 %s 处理超时 (%s): %v", e.RouteID, e.Timeout, e.Err)
+	}
+	return fmt.Sprintf("路由 %s 处理超时 (%s)", e.RouteID, e.Timeout)
+}
+
+// Is 使 errors.Is(err, ErrHandlerTimeout) 成立
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrHandlerTimeout
+}
+
+// Unwrap 返回处理器返回的错误
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
 // Route 路由结构
 type Route struct {
+	// ID 路由的唯一标识，为空时添加到路由器时自动生成
+	ID          string
 	Name        string
 	Pattern     string
 	Handler     Handler
 	Middlewares []Middleware
 	Matchers    []Matcher
+	// Plugin 注册该路由的插件，插件初始化期间添加的路由自动设置
+	Plugin string
+	// Priority 优先级，需在添加到路由器之前设置
+	Priority int
+	// Consume 为 true 时路由匹配并执行后不再执行之后的路由，等同于处理器调用 ctx.StopPropagation()
+	Consume bool
+	// Fallback 为 true 时仅在没有其他路由匹配时执行
+	Fallback bool
+	// Timeout 处理超时时间，为 0 时使用路由器的默认超时时间
+	Timeout time.Duration
+	// Command 通过 RegisterCommand 注册的命令定义，用于生成帮助
+	Command  *Command
+	disabled atomic.Bool
 }
 
 // NewRoute 创建新路由
@@ -164,6 +302,41 @@ func (r *Route) Match(matcher Matcher) *Route {
 	return r
 }
 
+// SetPriority 设置优先级
+func (r *Route) SetPriority(priority int) *Route {
+	r.Priority = priority
+	return r
+}
+
+// SetConsume 设置路由执行后是否停止执行之后的路由
+func (r *Route) SetConsume(consume bool) *Route {
+	r.Consume = consume
+	return r
+}
+
+// SetFallback 设置为兜底路由，仅在没有其他路由匹配时执行
+func (r *Route) SetFallback(fallback bool) *Route {
+	r.Fallback = fallback
+	return r
+}
+
+// SetTimeout 设置处理超时时间
+func (r *Route) SetTimeout(timeout time.Duration) *Route {
+	r.Timeout = timeout
+	return r
+}
+
+// SetID 设置路由ID
+func (r *Route) SetID(id string) *Route {
+	r.ID = id
+	return r
+}
+
+// IsEnabled 检查路由是否启用
+func (r *Route) IsEnabled() bool {
+	return !r.disabled.Load()
+}
+
 // SetPattern 设置模式
 func (r *Route) SetPattern(pattern string) *Route {
 	r.Pattern = pattern
@@ -190,10 +363,13 @@ func (r *Route) Execute(ctx *MessageContext) error {
 
 // Router 路由器
 type Router struct {
-	routes       []*Route
-	middlewares  []Middleware
-	errorHandler func(error, *MessageContext)
-	mu           sync.RWMutex
+	routes         []*Route
+	middlewares    []Middleware
+	errorHandler   func(error, *MessageContext)
+	pluginFilter   func(plugin string, ctx *MessageContext) bool
+	defaultTimeout time.Duration
+	nextID         uint64
+	mu             sync.RWMutex
 }
 
 // NewRouter 创建新路由器
@@ -202,6 +378,10 @@ func NewRouter() *Router {
 		routes:      make([]*Route, 0),
 		middlewares: make([]Middleware, 0),
 		errorHandler: func(err error, ctx *MessageContext) {
+			if errors.Is(err, ErrHandlerTimeout) {
+				logrus.Warnf("处理消息超时: %v", err)
+				return
+			}
 			logrus.Errorf("处理消息时发生错误: %v", err)
 		},
 	}
@@ -215,53 +395,255 @@ func (router *Router) Use(middleware Middleware) *Router {
 	return router
 }
 
-// AddRoute 添加路由
+// AddRoute 添加路由，ID 重复时记录错误并忽略该路由，需要处理错误时使用 Register
 func (router *Router) AddRoute(route *Route) *Router {
+	if _, err := router.Register(route); err != nil {
+		logrus.Errorf("添加路由 %s 失败: %v", route.Name, err)
+	}
+	return router
+}
+
+// Register 添加路由并返回路由ID，未设置 ID 时自动生成
+// 路由按优先级从高到低排列，相同优先级的路由排在已有路由之后
+func (router *Router) Register(route *Route) (string, error) {
 	router.mu.Lock()
 	defer router.mu.Unlock()
-	router.routes = append(router.routes, route)
-	return router
+
+	if route.ID == "" {
+		router.nextID++
+		route.ID = fmt.Sprintf("%s-%d", route.Name, router.nextID)
+	}
+	if router.indexOf(route.ID) >= 0 {
+		return "", fmt.Errorf("%w: %s", ErrRouteExists, route.ID)
+	}
+
+	router.insert(route)
+	return route.ID, nil
+}
+
+// RemoveRoute 移除路由，正在处理的消息不受影响
+func (router *Router) RemoveRoute(id string) error {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	index := router.indexOf(id)
+	if index < 0 {
+		return fmt.Errorf("%w: %s", ErrRouteNotFound, id)
+	}
+	router.remove(index)
+	return nil
+}
+
+// ReplaceRoute 使用新路由替换已有路由，新路由沿用原来的ID与启用状态
+// 优先级不变时保持原来的位置，否则按新的优先级重新排列
+func (router *Router) ReplaceRoute(id string, route *Route) error {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	index := router.indexOf(id)
+	if index < 0 {
+		return fmt.Errorf("%w: %s", ErrRouteNotFound, id)
+	}
+
+	old := router.routes[index]
+	route.ID = id
+	route.disabled.Store(old.disabled.Load())
+	if route.Plugin == "" {
+		route.Plugin = old.Plugin
+	}
+	if route.Priority == old.Priority {
+		// 复制切片，避免修改正在处理消息的快照
+		routes := make([]*Route, len(router.routes))
+		copy(routes, router.routes)
+		routes[index] = route
+		router.routes = routes
+		return nil
+	}
+	router.remove(index)
+	router.insert(route)
+	return nil
+}
+
+// SetRouteEnabled 启用或停用路由，停用的路由不参与匹配
+func (router *Router) SetRouteEnabled(id string, enabled bool) error {
+	route, ok := router.GetRoute(id)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRouteNotFound, id)
+	}
+	route.disabled.Store(!enabled)
+	return nil
+}
+
+// GetRoute 根据ID获取路由
+func (router *Router) GetRoute(id string) (*Route, bool) {
+	router.mu.RLock()
+	defer router.mu.RUnlock()
+	index := router.indexOf(id)
+	if index < 0 {
+		return nil, false
+	}
+	return router.routes[index], true
+}
+
+// indexOf 获取路由的位置，不存在时返回 -1，调用方需持有锁
+func (router *Router) indexOf(id string) int {
+	for i, route := range router.routes {
+		if route.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// insert 按优先级插入路由，调用方需持有写锁
+// 总是创建新的切片，Handle 中持有的快照不受影响
+func (router *Router) insert(route *Route) {
+	index := len(router.routes)
+	for i, r := range router.routes {
+		if r.Priority < route.Priority {
+			index = i
+			break
+		}
+	}
+	routes := make([]*Route, 0, len(router.routes)+1)
+	routes = append(routes, router.routes[:index]...)
+	routes = append(routes, route)
+	routes = append(routes, router.routes[index:]...)
+	router.routes = routes
+}
+
+// remove 移除指定位置的路由，调用方需持有写锁
+func (router *Router) remove(index int) {
+	routes := make([]*Route, 0, len(router.routes)-1)
+	routes = append(routes, router.routes[:index]...)
+	routes = append(routes, router.routes[index+1:]...)
+	router.routes = routes
+}
+
+// removeRoutes 移除满足条件的路由
+func (router *Router) removeRoutes(match func(*Route) bool) {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	routes := make([]*Route, 0, len(router.routes))
+	for _, route := range router.routes {
+		if !match(route) {
+			routes = append(routes, route)
+		}
+	}
+	router.routes = routes
 }
 
 // Handle 处理消息
+// 路由按优先级依次执行，处理器调用 ctx.StopPropagation() 或 Consume 路由执行后停止；
+// 没有普通路由匹配时再依次执行兜底路由；消息的上下文被取消后不再执行之后的路由
 func (router *Router) Handle(ctx *MessageContext) {
 	router.mu.RLock()
 	routes := make([]*Route, len(router.routes))
 	copy(routes, router.routes)
 	middlewares := make([]Middleware, len(router.middlewares))
 	copy(middlewares, router.middlewares)
+	pluginFilter := router.pluginFilter
+	defaultTimeout := router.defaultTimeout
 	router.mu.RUnlock()
 
-	// 为每个路由执行处理
-	for _, route := range routes {
-		// 创建完整的中间件链（全局中间件 + 路由中间件）
-		handler := route.Handler.Handle
-
-		// 先添加路由中间件
-		for i := len(route.Middlewares) - 1; i >= 0; i-- {
-			handler = route.Middlewares[i](handler)
+	matched := false
+	for _, fallback := range []bool{false, true} {
+		if fallback && matched {
+			return
 		}
-
-		// 再添加全局中间件
-		for i := len(middlewares) - 1; i >= 0; i-- {
-			handler = middlewares[i](handler)
-		}
-
-		// 检查路由匹配
-		matched := true
-		for _, matcher := range route.Matchers {
-			if !matcher.Match(ctx) {
-				matched = false
-				break
+		for _, route := range routes {
+			if route.Fallback != fallback || !route.IsEnabled() {
+				continue
+			}
+			// 插件在当前群中被禁用时跳过其路由
+			if route.Plugin != "" && pluginFilter != nil && !pluginFilter(route.Plugin, ctx) {
+				continue
+			}
+			if ctx.GetContext().Err() != nil {
+				return
+			}
+			if router.execute(route, middlewares, defaultTimeout, ctx) {
+				matched = true
+			}
+			if ctx.IsPropagationStopped() {
+				return
 			}
 		}
+	}
+}
 
-		if matched {
-			if err := handler(ctx); err != nil {
-				router.errorHandler(err, ctx)
-			}
+// execute 检查路由是否匹配，匹配时在超时时间内执行完整的中间件链，返回是否匹配
+func (router *Router) execute(route *Route, middlewares []Middleware, defaultTimeout time.Duration, ctx *MessageContext) bool {
+	// 检查路由匹配
+	for _, matcher := range route.Matchers {
+		if !matcher.Match(ctx) {
+			return false
 		}
 	}
+
+	// 创建完整的中间件链（全局中间件 + 路由中间件）
+	handler := route.Handler.Handle
+
+	// 先添加路由中间件
+	for i := len(route.Middlewares) - 1; i >= 0; i-- {
+		handler = route.Middlewares[i](handler)
+	}
+
+	// 再添加全局中间件
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	// 插件的 panic 不影响其他路由
+	if route.Plugin != "" {
+		handler = recoverPlugin(route.Plugin, handler)
+	}
+
+	if err := runWithTimeout(route, defaultTimeout, handler, ctx); err != nil {
+		router.errorHandler(err, ctx)
+	}
+	if route.Consume {
+		ctx.StopPropagation()
+	}
+	return true
+}
+
+// runWithTimeout 在路由的超时时间内执行处理器，超时后返回 TimeoutError
+func runWithTimeout(route *Route, defaultTimeout time.Duration, handler HandlerFunc, ctx *MessageContext) error {
+	timeout := route.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	if timeout <= 0 {
+		return handler(ctx)
+	}
+
+	parent := ctx.GetContext()
+	routeCtx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	ctx.WithContext(routeCtx)
+	defer ctx.WithContext(parent)
+
+	err := handler(ctx)
+	// 仅在本路由的超时时间到达时报告超时，上层上下文被取消时原样返回
+	if parent.Err() == nil && errors.Is(routeCtx.Err(), context.DeadlineExceeded) {
+		return &TimeoutError{RouteID: route.ID, Timeout: timeout, Err: err}
+	}
+	return err
+}
+
+// SetDefaultTimeout 设置路由的默认处理超时时间，为 0 时不限制
+func (router *Router) SetDefaultTimeout(timeout time.Duration) {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	router.defaultTimeout = timeout
+}
+
+// SetPluginFilter 设置插件路由的过滤器，返回 false 时跳过该插件的路由
+func (router *Router) SetPluginFilter(filter func(plugin string, ctx *MessageContext) bool) {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+	router.pluginFilter = filter
 }
 
 // SetErrorHandler 设置错误处理器
@@ -0,0 +1,260 @@
+package logic
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/LagrangeDev/LagrangeGo/message"
+	"github.com/vintcessun/WE-Assistant/config"
+)
+
+// recordRoute 创建执行时记录名称的路由
+func recordRoute(name string, calls *[]string) *Route {
+	return NewRoute(name, NewHandlerAdapter(func(ctx *MessageContext) error {
+		*calls = append(*calls, name)
+		return nil
+	}))
+}
+
+func routeIDs(router *Router) []string {
+	ids := make([]string, 0)
+	for _, route := range router.GetRoutes() {
+		ids = append(ids, route.ID)
+	}
+	return ids
+}
+
+func newRouterTestContext() *MessageContext {
+	return NewMessageContext(nil, &message.GroupMessage{GroupUin: 1, Sender: &message.Sender{Uin: 2}})
+}
+
+func TestRouterRegister(t *testing.T) {
+	router := NewRouter()
+	var calls []string
+
+	first, err := router.Register(recordRoute("a", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := router.Register(recordRoute("a", &calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Errorf("generated IDs should be unique, got %s twice", first)
+	}
+
+	if _, err := router.Register(recordRoute("b", &calls).SetID("fixed")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := router.Register(recordRoute("c", &calls).SetID("fixed")); !errors.Is(err, ErrRouteExists) {
+		t.Errorf("expected ErrRouteExists, got %v", err)
+	}
+	if route, ok := router.GetRoute("fixed"); !ok || route.Name != "b" {
+		t.Errorf("route fixed should still be b, got %+v", route)
+	}
+}
+
+func TestRouterPriorityOrder(t *testing.T) {
+	router := NewRouter()
+	var calls []string
+
+	router.Register(recordRoute("low", &calls).SetID("low").SetPriority(-1))
+	router.Register(recordRoute("default1", &calls).SetID("default1"))
+	router.Register(recordRoute("high", &calls).SetID("high").SetPriority(10))
+	router.Register(recordRoute("default2", &calls).SetID("default2"))
+
+	want := []string{"high", "default1", "default2", "low"}
+	if got := routeIDs(router); !reflect.DeepEqual(got, want) {
+		t.Errorf("routes = %v, want %v", got, want)
+	}
+
+	router.Handle(newRouterTestContext())
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+}
+
+func TestRouterRemove(t *testing.T) {
+	router := NewRouter()
+	var calls []string
+
+	router.Register(recordRoute("a", &calls).SetID("a"))
+	router.Register(recordRoute("b", &calls).SetID("b"))
+
+	if err := router.RemoveRoute("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := router.RemoveRoute("a"); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("expected ErrRouteNotFound, got %v", err)
+	}
+
+	router.Handle(newRouterTestContext())
+	if !reflect.DeepEqual(calls, []string{"b"}) {
+		t.Errorf("calls = %v, want [b]", calls)
+	}
+}
+
+func TestRouterReplace(t *testing.T) {
+	router := NewRouter()
+	var calls []string
+
+	router.Register(recordRoute("a", &calls).SetID("a"))
+	router.Register(recordRoute("b", &calls).SetID("b"))
+	router.Register(recordRoute("c", &calls).SetID("c"))
+
+	replacement := recordRoute("b2", &calls)
+	old, _ := router.GetRoute("b")
+	old.Plugin = "owner"
+	if err := router.ReplaceRoute("b", replacement); err != nil {
+		t.Fatal(err)
+	}
+	if replacement.ID != "b" || replacement.Plugin != "owner" {
+		t.Errorf("replacement should keep ID and plugin, got %s %s", replacement.ID, replacement.Plugin)
+	}
+	if got := routeIDs(router); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("same priority should keep the position, got %v", got)
+	}
+
+	if err := router.ReplaceRoute("b", recordRoute("b3", &calls).SetPriority(5)); err != nil {
+		t.Fatal(err)
+	}
+	if got := routeIDs(router); !reflect.DeepEqual(got, []string{"b", "a", "c"}) {
+		t.Errorf("new priority should reorder, got %v", got)
+	}
+
+	router.Handle(newRouterTestContext())
+	if !reflect.DeepEqual(calls, []string{"b3", "a", "c"}) {
+		t.Errorf("calls = %v, want [b3 a c]", calls)
+	}
+
+	if err := router.ReplaceRoute("missing", recordRoute("x", &calls)); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("expected ErrRouteNotFound, got %v", err)
+	}
+}
+
+func TestRouterSetRouteEnabled(t *testing.T) {
+	router := NewRouter()
+	var calls []string
+	router.Register(recordRoute("a", &calls).SetID("a"))
+
+	if err := router.SetRouteEnabled("a", false); err != nil {
+		t.Fatal(err)
+	}
+	router.Handle(newRouterTestContext())
+	if len(calls) != 0 {
+		t.Errorf("disabled route should not run, got %v", calls)
+	}
+
+	router.SetRouteEnabled("a", true)
+	router.Handle(newRouterTestContext())
+	if !reflect.DeepEqual(calls, []string{"a"}) {
+		t.Errorf("calls = %v, want [a]", calls)
+	}
+	if err := router.SetRouteEnabled("missing", true); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("expected ErrRouteNotFound, got %v", err)
+	}
+}
+
+func TestRouterReplaceKeepsDisabled(t *testing.T) {
+	router := NewRouter()
+	var calls []string
+	router.Register(recordRoute("a", &calls).SetID("a"))
+	router.SetRouteEnabled("a", false)
+
+	for _, replacement := range []*Route{recordRoute("a2", &calls), recordRoute("a3", &calls).SetPriority(5)} {
+		if err := router.ReplaceRoute("a", replacement); err != nil {
+			t.Fatal(err)
+		}
+	}
+	router.Handle(newRouterTestContext())
+	if len(calls) != 0 {
+		t.Errorf("replacing a disabled route should keep it disabled, got %v", calls)
+	}
+
+	router.SetRouteEnabled("a", true)
+	router.Handle(newRouterTestContext())
+	if !reflect.DeepEqual(calls, []string{"a3"}) {
+		t.Errorf("calls = %v, want [a3]", calls)
+	}
+}
+
+func TestRouterConsumeAndFallback(t *testing.T) {
+	router := NewRouter()
+	var calls []string
+	router.Register(recordRoute("fallback", &calls).SetFallback(true))
+	router.Register(recordRoute("first", &calls).SetConsume(true))
+	router.Register(recordRoute("second", &calls))
+
+	router.Handle(newRouterTestContext())
+	if !reflect.DeepEqual(calls, []string{"first"}) {
+		t.Errorf("calls = %v, want [first]", calls)
+	}
+
+	calls = nil
+	other := NewRouter()
+	other.Register(recordRoute("fallback", &calls).SetFallback(true))
+	other.Register(recordRoute("private", &calls).Match(NewMessageTypeMatcher("private")))
+	other.Handle(newRouterTestContext())
+	if !reflect.DeepEqual(calls, []string{"fallback"}) {
+		t.Errorf("calls = %v, want [fallback]", calls)
+	}
+}
+
+func TestRouterRemoveDuringHandle(t *testing.T) {
+	router := NewRouter()
+	var calls []string
+	router.Register(NewRoute("remover", NewHandlerAdapter(func(ctx *MessageContext) error {
+		calls = append(calls, "remover")
+		return router.RemoveRoute("next")
+	})).SetID("remover"))
+	router.Register(recordRoute("next", &calls).SetID("next"))
+
+	router.Handle(newRouterTestContext())
+	if !reflect.DeepEqual(calls, []string{"remover", "next"}) {
+		t.Errorf("routes removed while handling should not affect the current message, got %v", calls)
+	}
+
+	calls = nil
+	router.Handle(newRouterTestContext())
+	if !reflect.DeepEqual(calls, []string{"remover"}) {
+		t.Errorf("calls = %v, want [remover]", calls)
+	}
+}
+
+func TestRouterTimeout(t *testing.T) {
+	router := NewRouter()
+	var handled error
+	router.SetErrorHandler(func(err error, ctx *MessageContext) {
+		handled = err
+	})
+	router.Register(NewRoute("slow", NewHandlerAdapter(func(ctx *MessageContext) error {
+		<-ctx.GetContext().Done()
+		return ctx.GetContext().Err()
+	})).SetID("slow").SetTimeout(10 * time.Millisecond))
+
+	router.Handle(newRouterTestContext())
+	var timeoutErr *TimeoutError
+	if !errors.As(handled, &timeoutErr) || timeoutErr.RouteID != "slow" || !errors.Is(handled, ErrHandlerTimeout) {
+		t.Errorf("expected a TimeoutError for slow, got %v", handled)
+	}
+}
+
+func TestSystemPrompt(t *testing.T) {
+	cfg := config.Default()
+	cfg.LLM.GroupPrompt = "群聊提示词"
+	ctx := newRouterTestContext()
+	ctx.cfg = cfg
+	if got := ctx.SystemPrompt(); got != "群聊提示词" {
+		t.Errorf("SystemPrompt() = %q, want the configured prompt", got)
+	}
+
+	// 配置中移除 groupPrompt 后恢复内置提示词
+	ctx = newRouterTestContext()
+	ctx.cfg = config.Default()
+	if got := ctx.SystemPrompt(); got != GroupSystemPrompt {
+		t.Errorf("SystemPrompt() = %q, want the built-in prompt", got)
+	}
+}
@@ -0,0 +1,198 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 多轮会话: 处理器通过 ctx.Prompt 等待同一会话中同一用户的下一条消息。
+// 等待期间该用户的消息在分发前直接交给等待的处理器，不经过路由，开始等待前已在会话中排队的消息同样如此；
+// 收到 [chat] 的 cancelWords 中的关键词时结束等待并返回 ErrPromptCanceled。
+
+var (
+	// ErrPromptTimeout 等待回复超时
+	ErrPromptTimeout = errors.New("等待回复超时")
+	// ErrPromptCanceled 用户取消了会话
+	ErrPromptCanceled = errors.New("会话已取消")
+	// ErrPromptBusy 该用户已有等待回复的会话
+	ErrPromptBusy = errors.New("已有等待回复的会话")
+	// ErrSessionClosed 会话管理器已关闭
+	ErrSessionClosed = errors.New("会话已关闭")
+)
+
+// SessionManager 管理等待回复的多轮会话
+type SessionManager struct {
+	waiting   map[string]chan *MessageContext
+	closed    chan struct{}
+	closeOnce sync.Once
+	// claim 从会话的队列中取出满足条件的消息，由逻辑管理器设置
+	claim func(conv string, match func(*MessageContext) bool) *MessageContext
+	mu    sync.Mutex
+}
+
+// NewSessionManager 创建会话管理器
+func NewSessionManager() *SessionManager {
+	return &SessionManager{
+		waiting: make(map[string]chan *MessageContext),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Close 结束所有等待回复的会话，之后的 Prompt 直接返回 ErrSessionClosed
+func (sm *SessionManager) Close() {
+	sm.closeOnce.Do(func() {
+		close(sm.closed)
+	})
+}
+
+// Waiting 获取等待回复的会话数量
+func (sm *SessionManager) Waiting() int {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return len(sm.waiting)
+}
+
+// Deliver 将消息交给等待该用户回复的会话，返回是否已交付
+func (sm *SessionManager) Deliver(ctx *MessageContext) bool {
+	key, ok := sessionKey(ctx)
+	if !ok {
+		return false
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	reply, ok := sm.waiting[key]
+	if !ok {
+		return false
+	}
+	delete(sm.waiting, key)
+	reply <- ctx
+	return true
+}
+
+// wait 登记等待该用户的下一条消息，conv 为所在会话
+// 处理器开始等待前该用户的回复可能已在会话中排队，此时直接作为回复，之后到达的消息照常处理
+func (sm *SessionManager) wait(key string, conv string) (chan *MessageContext, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	select {
+	case <-sm.closed:
+		return nil, ErrSessionClosed
+	default:
+	}
+	if _, ok := sm.waiting[key]; ok {
+		return nil, ErrPromptBusy
+	}
+	reply := make(chan *MessageContext, 1)
+	if sm.claim != nil {
+		queued := sm.claim(conv, func(ctx *MessageContext) bool {
+			k, ok := sessionKey(ctx)
+			return ok && k == key
+		})
+		if queued != nil {
+			reply <- queued
+			return reply, nil
+		}
+	}
+	sm.waiting[key] = reply
+	return reply, nil
+}
+
+// abandon 取消登记，返回在取消前已交付的消息
+func (sm *SessionManager) abandon(key string, reply chan *MessageContext) *MessageContext {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.waiting[key] == reply {
+		delete(sm.waiting, key)
+		return nil
+	}
+	return <-reply
+}
+
+// sessionKey 获取会话等待回复的键，只有群聊与私聊消息可以参与会话
+func sessionKey(ctx *MessageContext) (string, bool) {
+	if _, ok := ctx.GetGroupMessage(); !ok {
+		if _, ok := ctx.GetPrivateMessage(); !ok {
+			return "", false
+		}
+	}
+	return fmt.Sprintf("%s:%d", conversationKey(ctx), ctx.SenderUin()), true
+}
+
+// Prompt 发送提示并等待同一会话中同一用户的下一条消息，text 为空时不发送提示
+// timeout <= 0 时使用 [chat] 的 promptTimeout；收到取消关键词时返回 ErrPromptCanceled，
+// 超时返回 ErrPromptTimeout，关闭时返回 ErrSessionClosed，消息的上下文被取消（路由超时）时返回上下文的错误
+func (mc *MessageContext) Prompt(text string, timeout time.Duration) (*MessageContext, error) {
+	if mc.sessions == nil {
+		return nil, errors.New("消息不支持多轮会话")
+	}
+	key, ok := sessionKey(mc)
+	if !ok {
+		return nil, ErrNoReplyTarget
+	}
+	scope := mc.Config()
+	if timeout <= 0 {
+		timeout = scope.PromptTimeout
+	}
+
+	// 先登记再发送提示，避免错过很快到达的回复
+	reply, err := mc.sessions.wait(key, conversationKey(mc))
+	if err != nil {
+		return nil, err
+	}
+	// 等待期间让出会话，同一会话中其他消息继续处理
+	if mc.detach != nil {
+		mc.detach()
+	}
+	if text != "" {
+		if _, err := mc.ReplyText(text); err != nil {
+			mc.sessions.abandon(key, reply)
+			return nil, err
+		}
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var answer *MessageContext
+	select {
+	case answer = <-reply:
+	case <-timer.C:
+		if answer = mc.sessions.abandon(key, reply); answer == nil {
+			return nil, ErrPromptTimeout
+		}
+	case <-mc.GetContext().Done():
+		if answer = mc.sessions.abandon(key, reply); answer == nil {
+			return nil, mc.GetContext().Err()
+		}
+	case <-mc.sessions.closed:
+		if answer = mc.sessions.abandon(key, reply); answer == nil {
+			return nil, ErrSessionClosed
+		}
+	}
+
+	answerText := strings.TrimSpace(answer.GetMessageText())
+	for _, word := range scope.CancelWords {
+		if answerText == word {
+			return answer, ErrPromptCanceled
+		}
+	}
+	return answer, nil
+}
+
+// PromptText 发送提示并等待回复的文本，参见 Prompt
+func (mc *MessageContext) PromptText(text string, timeout time.Duration) (string, error) {
+	answer, err := mc.Prompt(text, timeout)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(answer.GetMessageText()), nil
+}
+
+// isSessionEnd 检查错误是否为会话正常结束（取消、超时或关闭）
+func isSessionEnd(err error) bool {
+	return errors.Is(err, ErrPromptCanceled) || errors.Is(err, ErrPromptTimeout) || errors.Is(err, ErrSessionClosed)
+}
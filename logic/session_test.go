@@ -0,0 +1,195 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LagrangeDev/LagrangeGo/message"
+	"github.com/vintcessun/WE-Assistant/config"
+)
+
+// sessionTest 第一条 "start" 消息的处理器在 proceed 关闭后调用 Prompt，其余消息记录到 routed
+type sessionTest struct {
+	lm      *LogicManager
+	proceed chan struct{}
+	answer  chan string
+	errs    chan error
+	routed  []string
+	mu      sync.Mutex
+}
+
+func newSessionTest(t *testing.T, timeout time.Duration) *sessionTest {
+	t.Helper()
+	st := &sessionTest{
+		lm:      NewLogicManager(),
+		proceed: make(chan struct{}),
+		answer:  make(chan string, 1),
+		errs:    make(chan error, 1),
+	}
+	st.lm.SetConfig(config.Default())
+	st.lm.HandleGroupMessage(func(ctx *MessageContext) error {
+		text := ctx.GetMessageText()
+		if text != "start" {
+			st.mu.Lock()
+			st.routed = append(st.routed, text)
+			st.mu.Unlock()
+			return nil
+		}
+		<-st.proceed
+		answer, err := ctx.PromptText("", timeout)
+		st.answer <- answer
+		st.errs <- err
+		return err
+	})
+	st.lm.SetupEventListeners()
+	t.Cleanup(func() {
+		c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := st.lm.Shutdown(c); err != nil {
+			t.Errorf("shutdown: %v", err)
+		}
+	})
+	return st
+}
+
+func (st *sessionTest) send(sender uint32, text string) {
+	st.lm.processMessage(NewMessageContext(nil, &message.GroupMessage{
+		GroupUin: 1,
+		Sender:   &message.Sender{Uin: sender},
+		Elements: []message.IMessageElement{message.NewText(text)},
+	}))
+}
+
+func (st *sessionTest) getRouted() []string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return append([]string(nil), st.routed...)
+}
+
+func (st *sessionTest) result(t *testing.T) (string, error) {
+	t.Helper()
+	select {
+	case answer := <-st.answer:
+		return answer, <-st.errs
+	case <-time.After(2 * time.Second):
+		t.Fatal("prompt did not return")
+		return "", nil
+	}
+}
+
+// waitFor 等待条件成立
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPromptReceivesNextMessage(t *testing.T) {
+	st := newSessionTest(t, time.Second)
+	st.send(2, "start")
+	close(st.proceed)
+	waitFor(t, func() bool { return st.lm.GetSessionManager().Waiting() == 1 })
+
+	st.send(3, "other user")
+	st.send(2, "answer")
+
+	answer, err := st.result(t)
+	if err != nil || answer != "answer" {
+		t.Fatalf("got %q, %v", answer, err)
+	}
+	waitFor(t, func() bool { return len(st.getRouted()) == 1 })
+	if routed := st.getRouted(); routed[0] != "other user" {
+		t.Errorf("routed = %v, want [other user]", routed)
+	}
+}
+
+func TestPromptClaimsQueuedAnswer(t *testing.T) {
+	st := newSessionTest(t, time.Second)
+	st.send(2, "start")
+	waitFor(t, func() bool { return st.lm.GetDispatcherStats().Running == 1 })
+
+	// 处理器调用 Prompt 之前，回复已在同一会话中排队
+	st.send(3, "other user")
+	st.send(2, "answer")
+	st.send(2, "later")
+	if queued := st.lm.GetDispatcherStats().Queued; queued != 3 {
+		t.Fatalf("queued = %d, want 3", queued)
+	}
+	close(st.proceed)
+
+	answer, err := st.result(t)
+	if err != nil || answer != "answer" {
+		t.Fatalf("got %q, %v", answer, err)
+	}
+	waitFor(t, func() bool { return len(st.getRouted()) == 2 })
+	if routed := st.getRouted(); routed[0] != "other user" || routed[1] != "later" {
+		t.Errorf("routed = %v, want [other user later]", routed)
+	}
+}
+
+func TestPromptCancelWord(t *testing.T) {
+	st := newSessionTest(t, time.Second)
+	st.send(2, "start")
+	close(st.proceed)
+	waitFor(t, func() bool { return st.lm.GetSessionManager().Waiting() == 1 })
+
+	st.send(2, "取消")
+	if _, err := st.result(t); !errors.Is(err, ErrPromptCanceled) {
+		t.Errorf("expected ErrPromptCanceled, got %v", err)
+	}
+}
+
+func TestPromptTimeout(t *testing.T) {
+	st := newSessionTest(t, 20*time.Millisecond)
+	st.send(2, "start")
+	close(st.proceed)
+
+	if _, err := st.result(t); !errors.Is(err, ErrPromptTimeout) {
+		t.Errorf("expected ErrPromptTimeout, got %v", err)
+	}
+	if waiting := st.lm.GetSessionManager().Waiting(); waiting != 0 {
+		t.Errorf("waiting = %d after timeout", waiting)
+	}
+}
+
+func TestPromptEndsOnShutdown(t *testing.T) {
+	st := newSessionTest(t, time.Minute)
+	st.send(2, "start")
+	close(st.proceed)
+	waitFor(t, func() bool { return st.lm.GetSessionManager().Waiting() == 1 })
+
+	start := time.Now()
+	c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := st.lm.Shutdown(c); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.result(t); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("expected ErrSessionClosed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("shutdown took %v", elapsed)
+	}
+}
+
+func TestPromptBusy(t *testing.T) {
+	sm := NewSessionManager()
+	if _, err := sm.wait("k", "c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sm.wait("k", "c"); !errors.Is(err, ErrPromptBusy) {
+		t.Errorf("expected ErrPromptBusy, got %v", err)
+	}
+	sm.Close()
+	if _, err := sm.wait("other", "c"); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("expected ErrSessionClosed, got %v", err)
+	}
+}
@@ -1,51 +1,56 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"github.com/vintcessun/WE-Assistant/app"
-	"github.com/vintcessun/WE-Assistant/logic"
+	"github.com/vintcessun/WE-Assistant/config"
+	"github.com/vintcessun/WE-Assistant/utils"
 )
 
 func main() {
+	// 命令行参数优先于环境变量与配置文件
+	flags := config.NewFlags(flag.CommandLine)
+	flag.Parse()
+
 	// 使用依赖注入容器
 	container := app.NewContainer()
+	container.UseFlags(flags)
 	err := container.Initialize()
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
 	}
 
-	bot := container.GetBot()
-	logicManager := container.GetLogicManager()
-
-	// 登录
-	err = bot.Login()
+	// 按依赖顺序启动：登录各账号、开始监听、注册自定义逻辑
+	err = container.Start(context.Background())
 	if err != nil {
 		panic(err)
 	}
 
-	// 监听
-	bot.Listen()
-
-	// 注册自定义逻辑
-	logic.Manager = logicManager
-	logic.RegisterCustomLogic()
-
-	// 设置事件监听
-	logicManager.SetupEventListeners()
-
-	defer bot.Client().Release()
-	defer bot.Dumpsig()
-
 	// setup the main stop channel
 	mc := make(chan os.Signal, 2)
 	signal.Notify(mc, os.Interrupt, syscall.SIGTERM)
-	for {
-		switch <-mc {
-		case os.Interrupt, syscall.SIGTERM:
-			return
-		}
+	<-mc
+
+	// 再次收到信号时强制退出
+	go func() {
+		<-mc
+		utils.Warn("强制退出")
+		os.Exit(1)
+	}()
+
+	utils.Info("正在关闭...")
+	ctx, cancel := context.WithTimeout(context.Background(), container.GetShutdownTimeout())
+	defer cancel()
+	if err := container.Shutdown(ctx); err != nil {
+		utils.Errorf("部分组件未能正常关闭: %v", err)
+		os.Exit(1)
 	}
+	utils.Info("已关闭")
 }
@@ -0,0 +1,13 @@
+//go:build windows
+
+package utils
+
+import "os/exec"
+
+// setProcessGroup Windows 下不设置进程组
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup Windows 下仅结束服务器进程本身
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
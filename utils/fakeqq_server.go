@@ -18,9 +18,30 @@ import (
 	"github.com/LagrangeDev/LagrangeGo/message"
 )
 
+// FakeQQConfig FakeQQ-UI服务器配置
+type FakeQQConfig struct {
+	Dir          string        // FakeQQ-UI 项目目录
+	Port         int           // 起始端口
+	MaxPort      int           // 最大端口
+	StartTimeout time.Duration // 启动超时
+	LogFile      string        // 服务器输出日志文件
+}
+
+// DefaultFakeQQConfig 默认FakeQQ-UI服务器配置
+func DefaultFakeQQConfig() *FakeQQConfig {
+	return &FakeQQConfig{
+		Dir:          "FakeQQ-UI",
+		Port:         3001,
+		MaxPort:      3010,
+		StartTimeout: 30 * time.Second,
+		LogFile:      "fakeqq_server.log",
+	}
+}
+
 // FakeQQServer 管理FakeQQ-UI服务器
 type FakeQQServer struct {
 	port      int
+	config    *FakeQQConfig
 	cmd       *exec.Cmd
 	mu        sync.Mutex
 	isRunning bool
@@ -36,12 +57,21 @@ var (
 func GetFakeQQServer() *FakeQQServer {
 	serverOnce.Do(func() {
 		serverInstance = &FakeQQServer{
-			port: 3001, // 默认端口
+			port:   3001, // 默认端口
+			config: DefaultFakeQQConfig(),
 		}
 	})
 	return serverInstance
 }
 
+// SetConfig 设置服务器配置，在下次启动时生效
+func (s *FakeQQServer) SetConfig(config *FakeQQConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+	s.port = config.Port
+}
+
 // Start 启动FakeQQ-UI服务器
 func (s *FakeQQServer) Start() error {
 	s.mu.Lock()
@@ -66,10 +96,11 @@ func (s *FakeQQServer) Start() error {
 
 	// 启动服务器
 	cmd := exec.Command("pnpm", "run", "dev", "--", "--port", strconv.Itoa(port))
-	cmd.Dir = "FakeQQ-UI"
+	cmd.Dir = s.config.Dir
+	setProcessGroup(cmd)
 
 	// 重定向输出到日志文件
-	logFile, err := os.Create("fakeqq_server.log")
+	logFile, err := os.Create(s.config.LogFile)
 	if err != nil {
 		return fmt.Errorf("创建日志文件失败: %v", err)
 	}
@@ -86,7 +117,7 @@ func (s *FakeQQServer) Start() error {
 
 	// 等待服务器启动
 	if err := s.waitForServerReady(); err != nil {
-		s.Stop()
+		s.stop()
 		return fmt.Errorf("服务器启动超时: %v", err)
 	}
 
@@ -98,12 +129,16 @@ func (s *FakeQQServer) Start() error {
 func (s *FakeQQServer) Stop() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.stop()
+}
 
+// stop 停止服务器进程组，调用方需持有锁
+func (s *FakeQQServer) stop() error {
 	if !s.isRunning || s.cmd == nil {
 		return nil
 	}
 
-	if err := s.cmd.Process.Kill(); err != nil {
+	if err := killProcessGroup(s.cmd); err != nil {
 		return fmt.Errorf("停止服务器失败: %v", err)
 	}
 
@@ -130,7 +165,7 @@ func (s *FakeQQServer) GetBaseURL() string {
 // checkPnpm 检查pnpm是否可用
 func (s *FakeQQServer) checkPnpm() error {
 	cmd := exec.Command("pnpm", "--version")
-	cmd.Dir = "FakeQQ-UI"
+	cmd.Dir = s.config.Dir
 
 	output, err := cmd.Output()
 	if err != nil {
@@ -143,8 +178,8 @@ func (s *FakeQQServer) checkPnpm() error {
 
 // findAvailablePort 查找可用端口
 func (s *FakeQQServer) findAvailablePort() (int, error) {
-	// 从3001开始尝试
-	for port := 3001; port <= 3010; port++ {
+	// 从配置的起始端口开始尝试
+	for port := s.config.Port; port <= s.config.MaxPort; port++ {
 		if s.isPortAvailable(port) {
 			return port, nil
 		}
@@ -164,7 +199,7 @@ func (s *FakeQQServer) isPortAvailable(port int) bool {
 
 // waitForServerReady 等待服务器就绪
 func (s *FakeQQServer) waitForServerReady() error {
-	timeout := 30 * time.Second
+	timeout := s.config.StartTimeout
 	start := time.Now()
 
 	for time.Since(start) < timeout {
@@ -2,13 +2,55 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/openai/openai-go/v2"
 	"github.com/openai/openai-go/v2/option"
 )
 
-func openaiRequester(base_url, apiKey, model string, messages []openai.ChatCompletionMessageParamUnion, tools []openai.ChatCompletionToolUnionParam) (*openai.ChatCompletion, error) {
+// LLMConfig 大模型接口配置
+type LLMConfig struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	Timeout time.Duration
+}
+
+// ErrLLMNotConfigured 未配置 apiKey，大模型功能不可用
+var ErrLLMNotConfigured = errors.New("未配置大模型接口的 apiKey")
+
+// DefaultLLMConfig 默认大模型接口配置，不包含 apiKey
+func DefaultLLMConfig() *LLMConfig {
+	return &LLMConfig{
+		BaseURL: "https://api.deepseek.com/v1",
+		Model:   "deepseek-chat",
+		Timeout: 60 * time.Second,
+	}
+}
+
+var (
+	llmConfig   = DefaultLLMConfig()
+	llmConfigMu sync.RWMutex
+)
+
+// SetLLMConfig 设置大模型接口配置
+func SetLLMConfig(config *LLMConfig) {
+	llmConfigMu.Lock()
+	defer llmConfigMu.Unlock()
+	llmConfig = config
+}
+
+// GetLLMConfig 获取大模型接口配置
+func GetLLMConfig() *LLMConfig {
+	llmConfigMu.RLock()
+	defer llmConfigMu.RUnlock()
+	return llmConfig
+}
+
+func openaiRequester(base_url, apiKey, model string, timeout time.Duration, messages []openai.ChatCompletionMessageParamUnion, tools []openai.ChatCompletionToolUnionParam) (*openai.ChatCompletion, error) {
 	client := openai.NewClient(
 		option.WithAPIKey(apiKey),
 		option.WithBaseURL(base_url),
@@ -24,6 +66,11 @@ func openaiRequester(base_url, apiKey, model string, messages []openai.ChatCompl
 	}
 
 	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 	completion, err := client.Chat.Completions.New(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create chat completion: %w", err)
@@ -33,5 +80,9 @@ func openaiRequester(base_url, apiKey, model string, messages []openai.ChatCompl
 }
 
 func DeepSeekChat(messages []openai.ChatCompletionMessageParamUnion, tools []openai.ChatCompletionToolUnionParam) (*openai.ChatCompletion, error) {
-	return openaiRequester("https://api.deepseek.com/v1", "sk-775b4e8eebb4435f88ffc1fdcb37c7f6", "deepseek-chat", messages, tools)
+	config := GetLLMConfig()
+	if config.APIKey == "" {
+		return nil, ErrLLMNotConfigured
+	}
+	return openaiRequester(config.BaseURL, config.APIKey, config.Model, config.Timeout, messages, tools)
 }
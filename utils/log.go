@@ -369,6 +369,28 @@ func WithFields(fields map[string]interface{}) Logger {
 	return GetLogger().WithFields(fields)
 }
 
+// ParseLogLevel 解析日志级别名称
+func ParseLogLevel(level string) (LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "trace":
+		return TraceLevel, nil
+	case "debug":
+		return DebugLevel, nil
+	case "info", "":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	case "fatal":
+		return FatalLevel, nil
+	case "panic":
+		return PanicLevel, nil
+	default:
+		return InfoLevel, fmt.Errorf("未知的日志级别: %s", level)
+	}
+}
+
 // convertToLogrusLevel 转换日志等级
 func convertToLogrusLevel(level LogLevel) logrus.Level {
 	switch level {